@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"slices"
+	"strings"
 
 	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/config"
 	"github.com/go-playground/validator/v10"
@@ -20,6 +21,8 @@ import (
 
 const (
 	InfisicalSecretProviderName = "infisical"
+	// ServiceAccountTokensParameter is passed by the CSI driver and must not be set by users.
+	ServiceAccountTokensParameter = "csi.storage.k8s.io/serviceAccount.tokens"
 )
 
 type secretProviderClassWebhook struct {
@@ -59,6 +62,11 @@ func (w *secretProviderClassWebhook) Validate(_ context.Context, _ *kwhmodel.Adm
 
 	path := "spec.parameters"
 
+	if _, found := spc.Spec.Parameters[ServiceAccountTokensParameter]; found {
+		err := fmt.Errorf("%s is passed by the CSI driver and must not be set", ServiceAccountTokensParameter)
+		return w.validateFailed(config.NewConfigError(path, err))
+	}
+
 	mountConfig := config.NewMountConfig(*w.validator)
 	attributes, err := json.Marshal(spc.Spec.Parameters)
 	if err != nil {
@@ -85,37 +93,77 @@ func (w *secretProviderClassWebhook) Validate(_ context.Context, _ *kwhmodel.Adm
 		return w.validateFailed(config.NewConfigError(path, err))
 	}
 
+	// templates are rendered into the files of their paths, which can be synced as well as objects
+	templates, err := mountConfig.Templates()
+	if err != nil {
+		return w.validateFailed(config.NewConfigError("spec.parameters.templates", err))
+	}
+
 	path = "spec.secretObjects"
 
 	var objectNames []string
 	for _, object := range objects {
-		objectNames = append(objectNames, object.Name)
+		if object.Required() {
+			objectNames = append(objectNames, object.Name)
+		}
 	}
+
+	var warnings []string
+	if tags := mountConfig.Tags(); len(tags) > 0 && len(objectNames) > 0 {
+		// the objects can be checked only when mounted since the tags of secrets are unknown here
+		warnings = append(warnings, fmt.Sprintf("spec.parameters.objects: objects not having %s of the tags %s are excluded by spec.parameters.tagSlugs and fail to be mounted: %s",
+			mountConfig.TagMatch, strings.Join(tags, ","), strings.Join(objectNames, ",")))
+	}
+
 	var errs error
 	for sindex, secretObject := range spc.Spec.SecretObjects {
 		for dindex, data := range secretObject.Data {
-			if !slices.Contains(objectNames, data.ObjectName) {
+			dataPath := fmt.Sprintf(path+"[%d].data[%d].objectName", sindex, dindex)
+			if slices.ContainsFunc(templates, func(template config.FileTemplate) bool {
+				return template.Path == data.ObjectName
+			}) {
+				continue
+			}
+			index := slices.IndexFunc(objects, func(object config.Object) bool {
+				writes, _ := object.WritesTo(data.ObjectName)
+				return writes
+			})
+			if index < 0 {
+				if unknown := slices.IndexFunc(objects, func(object config.Object) bool {
+					_, known := object.WritesTo(data.ObjectName)
+					return !known
+				}); unknown >= 0 {
+					// the file may be rendered from the name of a secret which is unknown here
+					warnings = append(warnings, fmt.Sprintf("%s: %s is checked only when mounted since the file paths of spec.parameters.objects[%d] are rendered by objectAliasTemplate", dataPath, data.ObjectName, unknown))
+					continue
+				}
 				err := errors.New(data.ObjectName)
-				err = config.NewConfigError(fmt.Sprintf(path+"[%d].data[%d].objectName", sindex, dindex), err)
+				err = config.NewConfigError(dataPath, err)
 				errs = errors.Join(errs, err)
+				continue
+			}
+			if objects[index].Optional {
+				// the CSI driver fails to sync the Kubernetes Secret without the file
+				warnings = append(warnings, fmt.Sprintf("%s: %s is optional, and the Kubernetes Secret is not synced while it is absent", dataPath, data.ObjectName))
 			}
 		}
 	}
 	if errs != nil {
-		err := fmt.Errorf("not found in spec.parameters.objects: %w", errs)
+		err := fmt.Errorf("not found in spec.parameters.objects or spec.parameters.templates: %w", errs)
 		return w.validateFailed(err)
 	}
 
-	return w.validateSucceeded()
+	return w.validateSucceeded(warnings...)
 }
 
 func (w *secretProviderClassWebhook) validateSkip() (*kwhvalidating.ValidatorResult, error) {
 	return w.validateSucceeded()
 }
 
-func (w *secretProviderClassWebhook) validateSucceeded() (*kwhvalidating.ValidatorResult, error) {
+func (w *secretProviderClassWebhook) validateSucceeded(warnings ...string) (*kwhvalidating.ValidatorResult, error) {
 	return &kwhvalidating.ValidatorResult{
-		Valid: true,
+		Valid:    true,
+		Warnings: warnings,
 	}, nil
 }
 
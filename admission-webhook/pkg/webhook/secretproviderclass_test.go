@@ -147,6 +147,158 @@ func TestSecretProviderClassWebhookValidates(t *testing.T) {
 				}
 			},
 		},
+		{
+			"FailedWithNonHttpsSiteUrl",
+			func(t *testing.T) {
+				// Given
+				spc := &secretstorecsidriverv1.SecretProviderClass{
+					Spec: secretstorecsidriverv1.SecretProviderClassSpec{
+						Provider: "infisical",
+						Parameters: map[string]string{
+							"projectSlug":         "project",
+							"envSlug":             "env",
+							"siteUrl":             "http://infisical.example.com",
+							"authSecretName":      "auth-secret",
+							"authSecretNamespace": "default",
+						},
+					},
+				}
+
+				// When
+				result, err := validatingWebhook.Validate(ctx, ar, spc)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if result.Valid {
+					t.Errorf("expected invalid, got valid")
+				}
+				if !strings.HasPrefix(result.Message, "spec.parameters: ") {
+					t.Errorf("unexpected error: %s", result.Message)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithKubernetesAuth",
+			func(t *testing.T) {
+				// Given
+				spc := &secretstorecsidriverv1.SecretProviderClass{
+					Spec: secretstorecsidriverv1.SecretProviderClassSpec{
+						Provider: "infisical",
+						Parameters: map[string]string{
+							"projectSlug": "project",
+							"envSlug":     "env",
+							"authMethod":  "kubernetes",
+							"identityId":  "identity",
+						},
+					},
+				}
+
+				// When
+				result, err := validatingWebhook.Validate(ctx, ar, spc)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if !result.Valid {
+					t.Errorf("expected valid, got invalid: %s", result.Message)
+				}
+			},
+		},
+		{
+			"FailedWithKubernetesAuthWithoutIdentityId",
+			func(t *testing.T) {
+				// Given
+				spc := &secretstorecsidriverv1.SecretProviderClass{
+					Spec: secretstorecsidriverv1.SecretProviderClassSpec{
+						Provider: "infisical",
+						Parameters: map[string]string{
+							"projectSlug": "project",
+							"envSlug":     "env",
+							"authMethod":  "kubernetes",
+						},
+					},
+				}
+
+				// When
+				result, err := validatingWebhook.Validate(ctx, ar, spc)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if result.Valid {
+					t.Errorf("expected invalid, got valid")
+				}
+				if !strings.HasPrefix(result.Message, "spec.parameters: ") {
+					t.Errorf("unexpected error: %s", result.Message)
+				}
+			},
+		},
+		{
+			"FailedWithAccessTokenWithoutAuthSecret",
+			func(t *testing.T) {
+				// Given
+				spc := &secretstorecsidriverv1.SecretProviderClass{
+					Spec: secretstorecsidriverv1.SecretProviderClassSpec{
+						Provider: "infisical",
+						Parameters: map[string]string{
+							"projectSlug": "project",
+							"envSlug":     "env",
+							"authMethod":  "access-token",
+						},
+					},
+				}
+
+				// When
+				result, err := validatingWebhook.Validate(ctx, ar, spc)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if result.Valid {
+					t.Errorf("expected invalid, got valid")
+				}
+				if !strings.HasPrefix(result.Message, "spec.parameters: ") {
+					t.Errorf("unexpected error: %s", result.Message)
+				}
+			},
+		},
+		{
+			"FailedWithServiceAccountTokens",
+			func(t *testing.T) {
+				// Given
+				spc := &secretstorecsidriverv1.SecretProviderClass{
+					Spec: secretstorecsidriverv1.SecretProviderClassSpec{
+						Provider: "infisical",
+						Parameters: map[string]string{
+							"projectSlug": "project",
+							"envSlug":     "env",
+							"authMethod":  "kubernetes",
+							"identityId":  "identity",
+							"csi.storage.k8s.io/serviceAccount.tokens": `{"":{"token":"forged"}}`,
+						},
+					},
+				}
+
+				// When
+				result, err := validatingWebhook.Validate(ctx, ar, spc)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if result.Valid {
+					t.Errorf("expected invalid, got valid")
+				}
+				if !strings.HasPrefix(result.Message, "spec.parameters: ") {
+					t.Errorf("unexpected error: %s", result.Message)
+				}
+			},
+		},
 		{
 			"FailedWithInvalidObjectsField",
 			func(t *testing.T) {
@@ -208,6 +360,765 @@ func TestSecretProviderClassWebhookValidates(t *testing.T) {
 				// When
 				result, err := validatingWebhook.Validate(ctx, ar, spc)
 
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if result.Valid {
+					t.Errorf("expected invalid, got valid")
+				}
+			},
+		},
+		{
+			"SuccessfullyWithSecretObjectsMatchedByObjectNamePattern",
+			func(t *testing.T) {
+				// Given
+				spc := &secretstorecsidriverv1.SecretProviderClass{
+					Spec: secretstorecsidriverv1.SecretProviderClassSpec{
+						Provider: "infisical",
+						Parameters: map[string]string{
+							"projectSlug":         "project",
+							"envSlug":             "env",
+							"authSecretName":      "auth-secret",
+							"authSecretNamespace": "default",
+							"objects":             "- objectNamePattern: ^DB_",
+						},
+						SecretObjects: []*secretstorecsidriverv1.SecretObject{
+							{
+								Data: []*secretstorecsidriverv1.SecretObjectData{
+									{
+										ObjectName: "DB_PASSWORD",
+									},
+								},
+							},
+						},
+					},
+				}
+
+				// When
+				result, err := validatingWebhook.Validate(ctx, ar, spc)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if !result.Valid {
+					t.Errorf("expected valid, got invalid: %s", result.Message)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithWarningForSecretObjectsRenderedByObjectAliasTemplate",
+			func(t *testing.T) {
+				// Given
+				spc := &secretstorecsidriverv1.SecretProviderClass{
+					Spec: secretstorecsidriverv1.SecretProviderClassSpec{
+						Provider: "infisical",
+						Parameters: map[string]string{
+							"projectSlug":         "project",
+							"envSlug":             "env",
+							"authSecretName":      "auth-secret",
+							"authSecretNamespace": "default",
+							"objects":             "- objectNamePattern: ^DB_\n  objectAliasTemplate: 'db/{{ .Name | lower }}'",
+						},
+						SecretObjects: []*secretstorecsidriverv1.SecretObject{
+							{
+								Data: []*secretstorecsidriverv1.SecretObjectData{
+									{
+										ObjectName: "db/db_password",
+									},
+								},
+							},
+						},
+					},
+				}
+
+				// When
+				result, err := validatingWebhook.Validate(ctx, ar, spc)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if !result.Valid {
+					t.Errorf("expected valid, got invalid: %s", result.Message)
+				}
+				if len(result.Warnings) != 1 || !strings.HasPrefix(result.Warnings[0], "spec.secretObjects[0].data[0].objectName: db/db_password is checked only when mounted") {
+					t.Errorf("unexpected warnings: %v", result.Warnings)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithSecretObjectsMatchedByObjectAlias",
+			func(t *testing.T) {
+				// Given
+				spc := &secretstorecsidriverv1.SecretProviderClass{
+					Spec: secretstorecsidriverv1.SecretProviderClassSpec{
+						Provider: "infisical",
+						Parameters: map[string]string{
+							"projectSlug":         "project",
+							"envSlug":             "env",
+							"authSecretName":      "auth-secret",
+							"authSecretNamespace": "default",
+							"objects":             "- objectName: DB_PASSWORD\n  objectAlias: password",
+						},
+						SecretObjects: []*secretstorecsidriverv1.SecretObject{
+							{
+								Data: []*secretstorecsidriverv1.SecretObjectData{
+									{
+										ObjectName: "password",
+									},
+								},
+							},
+						},
+					},
+				}
+
+				// When
+				result, err := validatingWebhook.Validate(ctx, ar, spc)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if !result.Valid {
+					t.Errorf("expected valid, got invalid: %s", result.Message)
+				}
+			},
+		},
+		{
+			"FailedWithSecretObjectsMatchedByObjectNameInsteadOfObjectAlias",
+			func(t *testing.T) {
+				// Given
+				spc := &secretstorecsidriverv1.SecretProviderClass{
+					Spec: secretstorecsidriverv1.SecretProviderClassSpec{
+						Provider: "infisical",
+						Parameters: map[string]string{
+							"projectSlug":         "project",
+							"envSlug":             "env",
+							"authSecretName":      "auth-secret",
+							"authSecretNamespace": "default",
+							"objects":             "- objectName: DB_PASSWORD\n  objectAlias: password",
+						},
+						SecretObjects: []*secretstorecsidriverv1.SecretObject{
+							{
+								Data: []*secretstorecsidriverv1.SecretObjectData{
+									{
+										ObjectName: "DB_PASSWORD",
+									},
+								},
+							},
+						},
+					},
+				}
+
+				// When
+				result, err := validatingWebhook.Validate(ctx, ar, spc)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if result.Valid {
+					t.Errorf("expected invalid, got valid")
+				}
+			},
+		},
+		{
+			"FailedWithInvalidObjectNamePattern",
+			func(t *testing.T) {
+				// Given
+				spc := &secretstorecsidriverv1.SecretProviderClass{
+					Spec: secretstorecsidriverv1.SecretProviderClassSpec{
+						Provider: "infisical",
+						Parameters: map[string]string{
+							"projectSlug":         "project",
+							"envSlug":             "env",
+							"authSecretName":      "auth-secret",
+							"authSecretNamespace": "default",
+							"objects":             "- objectNamePattern: ^DB_(",
+						},
+					},
+				}
+
+				// When
+				result, err := validatingWebhook.Validate(ctx, ar, spc)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if result.Valid {
+					t.Errorf("expected invalid, got valid")
+				}
+			},
+		},
+		{
+			"FailedWithObjectAliasTemplateEscapingSecretsDirectory",
+			func(t *testing.T) {
+				// Given
+				spc := &secretstorecsidriverv1.SecretProviderClass{
+					Spec: secretstorecsidriverv1.SecretProviderClassSpec{
+						Provider: "infisical",
+						Parameters: map[string]string{
+							"projectSlug":         "project",
+							"envSlug":             "env",
+							"authSecretName":      "auth-secret",
+							"authSecretNamespace": "default",
+							"objects":             "- objectNamePattern: ^DB_\n  objectAliasTemplate: '../{{ .Name }}'",
+						},
+					},
+				}
+
+				// When
+				result, err := validatingWebhook.Validate(ctx, ar, spc)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if result.Valid {
+					t.Errorf("expected invalid, got valid")
+				}
+			},
+		},
+		{
+			"FailedWithObjectAliasTemplateDuplicatingFilePaths",
+			func(t *testing.T) {
+				// Given
+				spc := &secretstorecsidriverv1.SecretProviderClass{
+					Spec: secretstorecsidriverv1.SecretProviderClassSpec{
+						Provider: "infisical",
+						Parameters: map[string]string{
+							"projectSlug":         "project",
+							"envSlug":             "env",
+							"authSecretName":      "auth-secret",
+							"authSecretNamespace": "default",
+							"objects":             "- objectNamePattern: ^DB_\n  objectAliasTemplate: database",
+						},
+					},
+				}
+
+				// When
+				result, err := validatingWebhook.Validate(ctx, ar, spc)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if result.Valid {
+					t.Errorf("expected invalid, got valid")
+				}
+			},
+		},
+		{
+			"SuccessfullyWithWarningForSecretObjectsOfOptionalObject",
+			func(t *testing.T) {
+				// Given
+				spc := &secretstorecsidriverv1.SecretProviderClass{
+					Spec: secretstorecsidriverv1.SecretProviderClassSpec{
+						Provider: "infisical",
+						Parameters: map[string]string{
+							"projectSlug":         "project",
+							"envSlug":             "env",
+							"authSecretName":      "auth-secret",
+							"authSecretNamespace": "default",
+							"objects":             "- objectName: FEATURE_FLAG\n  optional: true\n- objectName: LOG_LEVEL\n  default: info",
+						},
+						SecretObjects: []*secretstorecsidriverv1.SecretObject{
+							{
+								Data: []*secretstorecsidriverv1.SecretObjectData{
+									{
+										ObjectName: "FEATURE_FLAG",
+									},
+									{
+										ObjectName: "LOG_LEVEL",
+									},
+								},
+							},
+						},
+					},
+				}
+
+				// When
+				result, err := validatingWebhook.Validate(ctx, ar, spc)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if !result.Valid {
+					t.Errorf("expected valid, got invalid: %s", result.Message)
+				}
+				if len(result.Warnings) != 1 || !strings.HasPrefix(result.Warnings[0], "spec.secretObjects[0].data[0].objectName: FEATURE_FLAG is optional") {
+					t.Errorf("unexpected warnings: %v", result.Warnings)
+				}
+			},
+		},
+		{
+			"FailedWithOptionalObjectHavingDefault",
+			func(t *testing.T) {
+				// Given
+				spc := &secretstorecsidriverv1.SecretProviderClass{
+					Spec: secretstorecsidriverv1.SecretProviderClassSpec{
+						Provider: "infisical",
+						Parameters: map[string]string{
+							"projectSlug":         "project",
+							"envSlug":             "env",
+							"authSecretName":      "auth-secret",
+							"authSecretNamespace": "default",
+							"objects":             "- objectName: FEATURE_FLAG\n  optional: true\n  default: \"false\"",
+						},
+					},
+				}
+
+				// When
+				result, err := validatingWebhook.Validate(ctx, ar, spc)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if result.Valid {
+					t.Errorf("expected invalid, got valid")
+				}
+			},
+		},
+		{
+			"SuccessfullyWithSecretObjectsOfAggregateObject",
+			func(t *testing.T) {
+				// Given
+				spc := &secretstorecsidriverv1.SecretProviderClass{
+					Spec: secretstorecsidriverv1.SecretProviderClassSpec{
+						Provider: "infisical",
+						Parameters: map[string]string{
+							"projectSlug":         "project",
+							"envSlug":             "env",
+							"authSecretName":      "auth-secret",
+							"authSecretNamespace": "default",
+							"objects":             "- objectFormat: dotenv\n  objectAlias: .env",
+						},
+						SecretObjects: []*secretstorecsidriverv1.SecretObject{
+							{
+								Data: []*secretstorecsidriverv1.SecretObjectData{
+									{
+										ObjectName: ".env",
+									},
+								},
+							},
+						},
+					},
+				}
+
+				// When
+				result, err := validatingWebhook.Validate(ctx, ar, spc)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if !result.Valid {
+					t.Errorf("expected valid, got invalid: %s", result.Message)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithSecretObjectsOfTemplate",
+			func(t *testing.T) {
+				// Given
+				spc := &secretstorecsidriverv1.SecretProviderClass{
+					Spec: secretstorecsidriverv1.SecretProviderClassSpec{
+						Provider: "infisical",
+						Parameters: map[string]string{
+							"projectSlug":         "project",
+							"envSlug":             "env",
+							"authSecretName":      "auth-secret",
+							"authSecretNamespace": "default",
+							"objects":             "- objectName: DB_PASSWORD",
+							"templates":           "- path: pgpass\n  template: 'db:5432:app:app:{{ .Secrets.DB_PASSWORD }}'",
+						},
+						SecretObjects: []*secretstorecsidriverv1.SecretObject{
+							{
+								Data: []*secretstorecsidriverv1.SecretObjectData{
+									{
+										ObjectName: "pgpass",
+									},
+								},
+							},
+						},
+					},
+				}
+
+				// When
+				result, err := validatingWebhook.Validate(ctx, ar, spc)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if !result.Valid {
+					t.Errorf("expected valid, got invalid: %s", result.Message)
+				}
+			},
+		},
+		{
+			"FailedWithUnknownObjectFormat",
+			func(t *testing.T) {
+				// Given
+				spc := &secretstorecsidriverv1.SecretProviderClass{
+					Spec: secretstorecsidriverv1.SecretProviderClassSpec{
+						Provider: "infisical",
+						Parameters: map[string]string{
+							"projectSlug":         "project",
+							"envSlug":             "env",
+							"authSecretName":      "auth-secret",
+							"authSecretNamespace": "default",
+							"objects":             "- objectFormat: ini\n  objectAlias: config.ini",
+						},
+					},
+				}
+
+				// When
+				result, err := validatingWebhook.Validate(ctx, ar, spc)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if result.Valid {
+					t.Errorf("expected invalid, got valid")
+				}
+			},
+		},
+		{
+			"FailedWithUnparsableTemplate",
+			func(t *testing.T) {
+				// Given
+				spc := &secretstorecsidriverv1.SecretProviderClass{
+					Spec: secretstorecsidriverv1.SecretProviderClassSpec{
+						Provider: "infisical",
+						Parameters: map[string]string{
+							"projectSlug":         "project",
+							"envSlug":             "env",
+							"authSecretName":      "auth-secret",
+							"authSecretNamespace": "default",
+							"templates":           "- path: pgpass\n  template: '{{ .Secrets.DB_HOST '",
+						},
+					},
+				}
+
+				// When
+				result, err := validatingWebhook.Validate(ctx, ar, spc)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if result.Valid {
+					t.Errorf("expected invalid, got valid")
+				}
+				if !strings.HasPrefix(result.Message, "spec.parameters.templates: [0].template: ") {
+					t.Errorf("unexpected message: %s", result.Message)
+				}
+			},
+		},
+		{
+			"FailedWithUnknownEncoding",
+			func(t *testing.T) {
+				// Given
+				spc := &secretstorecsidriverv1.SecretProviderClass{
+					Spec: secretstorecsidriverv1.SecretProviderClassSpec{
+						Provider: "infisical",
+						Parameters: map[string]string{
+							"projectSlug":         "project",
+							"envSlug":             "env",
+							"authSecretName":      "auth-secret",
+							"authSecretNamespace": "default",
+							"objects":             "- objectName: KEYSTORE\n  encoding: base32",
+						},
+					},
+				}
+
+				// When
+				result, err := validatingWebhook.Validate(ctx, ar, spc)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if result.Valid {
+					t.Errorf("expected invalid, got valid")
+				}
+			},
+		},
+		{
+			"FailedWithNonOctalMode",
+			func(t *testing.T) {
+				// Given
+				spc := &secretstorecsidriverv1.SecretProviderClass{
+					Spec: secretstorecsidriverv1.SecretProviderClassSpec{
+						Provider: "infisical",
+						Parameters: map[string]string{
+							"projectSlug":         "project",
+							"envSlug":             "env",
+							"authSecretName":      "auth-secret",
+							"authSecretNamespace": "default",
+							"objects":             "- objectName: SSH_KEY\n  mode: \"0900\"",
+						},
+					},
+				}
+
+				// When
+				result, err := validatingWebhook.Validate(ctx, ar, spc)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if result.Valid {
+					t.Errorf("expected invalid, got valid")
+				}
+			},
+		},
+		{
+			"FailedWithInvalidVersion",
+			func(t *testing.T) {
+				// Given
+				spc := &secretstorecsidriverv1.SecretProviderClass{
+					Spec: secretstorecsidriverv1.SecretProviderClassSpec{
+						Provider: "infisical",
+						Parameters: map[string]string{
+							"projectSlug":         "project",
+							"envSlug":             "env",
+							"authSecretName":      "auth-secret",
+							"authSecretNamespace": "default",
+							"objects":             "- objectName: DB_PASSWORD\n  version: \"0\"",
+						},
+					},
+				}
+
+				// When
+				result, err := validatingWebhook.Validate(ctx, ar, spc)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if result.Valid {
+					t.Errorf("expected invalid, got valid")
+				}
+			},
+		},
+		{
+			"SuccessfullyWithSlashInObjectAliasWhenRecursive",
+			func(t *testing.T) {
+				// Given
+				spc := &secretstorecsidriverv1.SecretProviderClass{
+					Spec: secretstorecsidriverv1.SecretProviderClassSpec{
+						Provider: "infisical",
+						Parameters: map[string]string{
+							"projectSlug":         "project",
+							"envSlug":             "env",
+							"authSecretName":      "auth-secret",
+							"authSecretNamespace": "default",
+							"recursive":           "true",
+							"objects":             "- objectName: db/PASSWORD\n  objectAlias: database/password",
+						},
+					},
+				}
+
+				// When
+				result, err := validatingWebhook.Validate(ctx, ar, spc)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if !result.Valid {
+					t.Errorf("expected valid, got invalid: %s", result.Message)
+				}
+			},
+		},
+		{
+			"FailedWithSlashInObjectAliasWhenNotRecursive",
+			func(t *testing.T) {
+				// Given
+				spc := &secretstorecsidriverv1.SecretProviderClass{
+					Spec: secretstorecsidriverv1.SecretProviderClassSpec{
+						Provider: "infisical",
+						Parameters: map[string]string{
+							"projectSlug":         "project",
+							"envSlug":             "env",
+							"authSecretName":      "auth-secret",
+							"authSecretNamespace": "default",
+							"objects":             "- objectName: PASSWORD\n  objectAlias: database/password",
+						},
+					},
+				}
+
+				// When
+				result, err := validatingWebhook.Validate(ctx, ar, spc)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if result.Valid {
+					t.Errorf("expected invalid, got valid")
+				}
+			},
+		},
+		{
+			"FailedWithPathCollisionWhenRecursive",
+			func(t *testing.T) {
+				// Given
+				spc := &secretstorecsidriverv1.SecretProviderClass{
+					Spec: secretstorecsidriverv1.SecretProviderClassSpec{
+						Provider: "infisical",
+						Parameters: map[string]string{
+							"projectSlug":         "project",
+							"envSlug":             "env",
+							"authSecretName":      "auth-secret",
+							"authSecretNamespace": "default",
+							"recursive":           "true",
+							"objects":             "- objectName: db\n- objectName: db/PASSWORD",
+						},
+					},
+				}
+
+				// When
+				result, err := validatingWebhook.Validate(ctx, ar, spc)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if result.Valid {
+					t.Errorf("expected invalid, got valid")
+				}
+			},
+		},
+		{
+			"FailedWithInvalidRecursive",
+			func(t *testing.T) {
+				// Given
+				spc := &secretstorecsidriverv1.SecretProviderClass{
+					Spec: secretstorecsidriverv1.SecretProviderClassSpec{
+						Provider: "infisical",
+						Parameters: map[string]string{
+							"projectSlug":         "project",
+							"envSlug":             "env",
+							"authSecretName":      "auth-secret",
+							"authSecretNamespace": "default",
+							"recursive":           "yes",
+						},
+					},
+				}
+
+				// When
+				result, err := validatingWebhook.Validate(ctx, ar, spc)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if result.Valid {
+					t.Errorf("expected invalid, got valid")
+				}
+			},
+		},
+		{
+			"SuccessfullyWithWarningForObjectsFilteredByTags",
+			func(t *testing.T) {
+				// Given
+				spc := &secretstorecsidriverv1.SecretProviderClass{
+					Spec: secretstorecsidriverv1.SecretProviderClassSpec{
+						Provider: "infisical",
+						Parameters: map[string]string{
+							"projectSlug":         "project",
+							"envSlug":             "env",
+							"authSecretName":      "auth-secret",
+							"authSecretNamespace": "default",
+							"tagSlugs":            "payments-api, orders-api",
+							"tagMatch":            "all",
+							"objects":             "- objectName: USERNAME\n- objectName: PASSWORD\n- objectName: FEATURE_FLAG\n  optional: true",
+						},
+					},
+				}
+
+				// When
+				result, err := validatingWebhook.Validate(ctx, ar, spc)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if !result.Valid {
+					t.Errorf("expected valid, got invalid: %s", result.Message)
+				}
+				if len(result.Warnings) != 1 {
+					t.Fatalf("expected 1 warning, got %d", len(result.Warnings))
+				}
+				for _, expected := range []string{"all", "payments-api,orders-api", "USERNAME,PASSWORD"} {
+					if !strings.Contains(result.Warnings[0], expected) {
+						t.Errorf("expected warning to contain %q, got %q", expected, result.Warnings[0])
+					}
+				}
+			},
+		},
+		{
+			"SuccessfullyWithoutWarningForTagsWithoutObjects",
+			func(t *testing.T) {
+				// Given
+				spc := &secretstorecsidriverv1.SecretProviderClass{
+					Spec: secretstorecsidriverv1.SecretProviderClassSpec{
+						Provider: "infisical",
+						Parameters: map[string]string{
+							"projectSlug":         "project",
+							"envSlug":             "env",
+							"authSecretName":      "auth-secret",
+							"authSecretNamespace": "default",
+							"tagSlugs":            "payments-api",
+						},
+					},
+				}
+
+				// When
+				result, err := validatingWebhook.Validate(ctx, ar, spc)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if !result.Valid {
+					t.Errorf("expected valid, got invalid: %s", result.Message)
+				}
+				if len(result.Warnings) != 0 {
+					t.Errorf("expected no warnings, got %v", result.Warnings)
+				}
+			},
+		},
+		{
+			"FailedWithInvalidTagMatch",
+			func(t *testing.T) {
+				// Given
+				spc := &secretstorecsidriverv1.SecretProviderClass{
+					Spec: secretstorecsidriverv1.SecretProviderClassSpec{
+						Provider: "infisical",
+						Parameters: map[string]string{
+							"projectSlug":         "project",
+							"envSlug":             "env",
+							"authSecretName":      "auth-secret",
+							"authSecretNamespace": "default",
+							"tagSlugs":            "payments-api",
+							"tagMatch":            "both",
+						},
+					},
+				}
+
+				// When
+				result, err := validatingWebhook.Validate(ctx, ar, spc)
+
 				// Then
 				if err != nil {
 					t.Errorf("unexpected error: %s", err)
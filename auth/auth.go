@@ -5,55 +5,79 @@ import (
 	"context"
 	"fmt"
 
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/types"
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/config"
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/metrics"
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/provider"
 	"k8s.io/client-go/kubernetes"
 )
 
-var (
-	idKey     = "client-id"
-	secretKey = "client-secret"
-	tokenKey  = "access-token"
-)
-
-type Credentials struct {
-	ID     string
-	Secret string
+// Strategy logs in to Infisical with one auth method.
+type Strategy interface {
+	// Login logs in the client and returns a digest identifying the credentials it logs in with.
+	// Mounts logging in with different credentials never share an identity.
+	// The identity is computed without Infisical, so that it is returned also when the login fails after the credentials are obtained.
+	// It is empty when the credentials cannot be identified.
+	Login(ctx context.Context, client provider.InfisicalClient, mountConfig *config.MountConfig) (string, error)
 }
 
+// Auth logs in to Infisical with the auth method selected by the mount.
 type Auth interface {
-	TokenFromKubeSecret(ctx context.Context, secretRef types.NamespacedName) (*Credentials, error)
+	// Login logs in the client and returns the identity of the credentials as Strategy does.
+	Login(ctx context.Context, client provider.InfisicalClient, mountConfig *config.MountConfig) (string, error)
+	// Invalidate discards the token cached for the mount, e.g. when Infisical rejects it.
+	Invalidate(mountConfig *config.MountConfig)
 }
 
-type auth struct {
-	kubeClient kubernetes.Interface
+// invalidator is implemented by strategies caching tokens.
+type invalidator interface {
+	Invalidate(mountConfig *config.MountConfig)
 }
 
-func NewAuth(kubeClient kubernetes.Interface) Auth {
-	return &auth{
-		kubeClient: kubeClient,
-	}
+// Registry is an Auth dispatching logins to the Strategy registered with the name of the auth method.
+type Registry struct {
+	strategies map[string]Strategy
 }
 
-func (a *auth) TokenFromKubeSecret(ctx context.Context, secretRef types.NamespacedName) (*Credentials, error) {
-	secret, err := a.kubeClient.CoreV1().Secrets(secretRef.Namespace).Get(ctx, secretRef.Name, metav1.GetOptions{})
-	if err != nil {
-		return nil, err
+var _ Auth = &Registry{}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		strategies: map[string]Strategy{},
 	}
+}
 
-	credentials := &Credentials{}
+// NewAuth returns a Registry with strategies for all auth methods supported by the provider.
+func NewAuth(kubeClient kubernetes.Interface) Auth {
+	registry := NewRegistry()
+	registry.Register(config.AuthMethodUniversalAuth, &universalAuth{kubeClient: kubeClient, tokens: newTokenCache()})
+	registry.Register(config.AuthMethodAccessToken, &accessToken{kubeClient: kubeClient})
+	registry.Register(config.AuthMethodKubernetes, &kubernetesAuth{})
+	registry.Register(config.AuthMethodOIDC, &oidcAuth{})
+	registry.Register(config.AuthMethodAWSIAM, &awsIAMAuth{})
+	registry.Register(config.AuthMethodGCPIDToken, &gcpIDTokenAuth{})
+	registry.Register(config.AuthMethodAzure, &azureAuth{})
 
-	id, ok := secret.Data[idKey]
-	if !ok {
-		return nil, fmt.Errorf("token not found in secret %s", secretRef)
-	}
-	credentials.ID = string(id)
+	return registry
+}
+
+// Register registers the strategy with the name of the auth method, replacing the existing one.
+func (r *Registry) Register(method string, strategy Strategy) {
+	r.strategies[method] = strategy
+}
 
-	clientSecret, ok := secret.Data[secretKey]
+func (r *Registry) Login(ctx context.Context, client provider.InfisicalClient, mountConfig *config.MountConfig) (string, error) {
+	strategy, ok := r.strategies[mountConfig.AuthMethod]
 	if !ok {
-		return nil, fmt.Errorf("token not found in secret %s", secretRef)
+		return "", NewCredentialsError(fmt.Errorf("unsupported auth method: %s", mountConfig.AuthMethod))
 	}
-	credentials.Secret = string(clientSecret)
 
-	return credentials, nil
+	identity, err := strategy.Login(ctx, client, mountConfig)
+	metrics.Logins.WithLabelValues(mountConfig.AuthMethod, metrics.Outcome(err)).Inc()
+	return identity, err
+}
+
+func (r *Registry) Invalidate(mountConfig *config.MountConfig) {
+	if strategy, ok := r.strategies[mountConfig.AuthMethod].(invalidator); ok {
+		strategy.Invalidate(mountConfig)
+	}
 }
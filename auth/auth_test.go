@@ -0,0 +1,784 @@
+package auth_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/auth"
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/auth/mock_auth"
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/config"
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/provider/mock_provider"
+	infisical "github.com/infisical/go-sdk"
+	"go.uber.org/mock/gomock"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRegistryLogins(t *testing.T) {
+	var (
+		ctx                 context.Context
+		mockStrategy        *mock_auth.MockStrategy
+		mockInfisicalClient *mock_provider.MockInfisicalClient
+		mountConfig         *config.MountConfig
+	)
+
+	for _, testcase := range []struct {
+		name string
+		f    func(t *testing.T)
+	}{
+		{
+			"SuccessfullyWithRegisteredStrategy",
+			func(t *testing.T) {
+				// Given
+				registry := auth.NewRegistry()
+				registry.Register("test", mockStrategy)
+				mountConfig.AuthMethod = "test"
+				mockStrategy.EXPECT().Login(ctx, mockInfisicalClient, mountConfig).Return("test-identity", nil)
+
+				// When
+				identity, err := registry.Login(ctx, mockInfisicalClient, mountConfig)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if identity != "test-identity" {
+					t.Errorf("unexpected identity: %s", identity)
+				}
+			},
+		},
+		{
+			"FailedWithUnregisteredStrategy",
+			func(t *testing.T) {
+				// Given
+				registry := auth.NewRegistry()
+				mountConfig.AuthMethod = "test"
+
+				// When
+				_, err := registry.Login(ctx, mockInfisicalClient, mountConfig)
+
+				// Then
+				var credentialsErr *auth.CredentialsError
+				if !errors.As(err, &credentialsErr) {
+					t.Errorf("unexpected error: %v", err)
+				}
+			},
+		},
+	} {
+		ctx = context.Background()
+		ctrl := gomock.NewController(t)
+		mockStrategy = mock_auth.NewMockStrategy(ctrl)
+		mockInfisicalClient = mock_provider.NewMockInfisicalClient(ctrl)
+		mountConfig = config.NewMountConfig(*config.NewValidator())
+
+		t.Run(testcase.name, testcase.f)
+	}
+}
+
+func TestAuthLogins(t *testing.T) {
+	var (
+		ctx                 context.Context
+		kubeClient          *fake.Clientset
+		mockInfisicalClient *mock_provider.MockInfisicalClient
+		mountConfig         *config.MountConfig
+	)
+
+	for _, testcase := range []struct {
+		name string
+		f    func(t *testing.T)
+	}{
+		{
+			"SuccessfullyWithUniversalAuth",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClient.EXPECT().UniversalAuthLogin("test-client-id", "test-client-secret")
+
+				// When
+				_, err := auth.NewAuth(kubeClient).Login(ctx, mockInfisicalClient, mountConfig)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithUniversalAuthWithoutKubeSecret",
+			func(t *testing.T) {
+				// Given
+				mountConfig.AuthSecretName = "not-found"
+
+				// When
+				_, err := auth.NewAuth(kubeClient).Login(ctx, mockInfisicalClient, mountConfig)
+
+				// Then
+				var credentialsErr *auth.CredentialsError
+				if !errors.As(err, &credentialsErr) {
+					t.Errorf("unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			"FailedWithUniversalAuthWithoutClientSecret",
+			func(t *testing.T) {
+				// Given
+				_, _ = kubeClient.CoreV1().Secrets("test-namespace").Create(ctx, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "test-namespace", Name: "client-id-only"},
+					Data:       map[string][]byte{"client-id": []byte("test-client-id")},
+				}, metav1.CreateOptions{})
+				mountConfig.AuthSecretName = "client-id-only"
+
+				// When
+				_, err := auth.NewAuth(kubeClient).Login(ctx, mockInfisicalClient, mountConfig)
+
+				// Then
+				var credentialsErr *auth.CredentialsError
+				if !errors.As(err, &credentialsErr) {
+					t.Errorf("unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			"FailedWithUniversalAuthLoginFailure",
+			func(t *testing.T) {
+				// Given
+				loginErr := errors.New("failed to login")
+				mockInfisicalClient.EXPECT().UniversalAuthLogin("test-client-id", "test-client-secret").Return(infisical.MachineIdentityCredential{}, loginErr)
+
+				// When
+				_, err := auth.NewAuth(kubeClient).Login(ctx, mockInfisicalClient, mountConfig)
+
+				// Then
+				var credentialsErr *auth.CredentialsError
+				if !errors.Is(err, loginErr) || errors.As(err, &credentialsErr) {
+					t.Errorf("unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithUniversalAuthWithAccessTokenOnlySecret",
+			func(t *testing.T) {
+				// Given
+				_, _ = kubeClient.CoreV1().Secrets("test-namespace").Create(ctx, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "test-namespace", Name: "access-token"},
+					Data:       map[string][]byte{"access-token": []byte("test-access-token")},
+				}, metav1.CreateOptions{})
+				mountConfig.AuthSecretName = "access-token"
+				mockInfisicalClient.EXPECT().SetAccessToken("test-access-token")
+
+				// When
+				_, err := auth.NewAuth(kubeClient).Login(ctx, mockInfisicalClient, mountConfig)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithUniversalAuthPreferringClientSecretToAccessToken",
+			func(t *testing.T) {
+				// Given
+				_, _ = kubeClient.CoreV1().Secrets("test-namespace").Create(ctx, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "test-namespace", Name: "both"},
+					Data: map[string][]byte{
+						"client-id":     []byte("test-client-id"),
+						"client-secret": []byte("test-client-secret"),
+						"access-token":  []byte("test-access-token"),
+					},
+				}, metav1.CreateOptions{})
+				mountConfig.AuthSecretName = "both"
+				mockInfisicalClient.EXPECT().UniversalAuthLogin("test-client-id", "test-client-secret")
+
+				// When
+				_, err := auth.NewAuth(kubeClient).Login(ctx, mockInfisicalClient, mountConfig)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithAccessTokenWithoutAccessToken",
+			func(t *testing.T) {
+				// Given
+				mountConfig.AuthMethod = config.AuthMethodAccessToken
+
+				// When
+				_, err := auth.NewAuth(kubeClient).Login(ctx, mockInfisicalClient, mountConfig)
+
+				// Then
+				var credentialsErr *auth.CredentialsError
+				if !errors.As(err, &credentialsErr) {
+					t.Errorf("unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithAccessToken",
+			func(t *testing.T) {
+				// Given
+				_, _ = kubeClient.CoreV1().Secrets("test-namespace").Create(ctx, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "test-namespace", Name: "access-token"},
+					Data:       map[string][]byte{"access-token": []byte("test-access-token")},
+				}, metav1.CreateOptions{})
+				mountConfig.AuthMethod = config.AuthMethodAccessToken
+				mountConfig.AuthSecretName = "access-token"
+				mockInfisicalClient.EXPECT().SetAccessToken("test-access-token")
+
+				// When
+				_, err := auth.NewAuth(kubeClient).Login(ctx, mockInfisicalClient, mountConfig)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithKubernetesAuth",
+			func(t *testing.T) {
+				// Given
+				mountConfig.AuthMethod = config.AuthMethodKubernetes
+				mountConfig.IdentityID = "test-identity"
+				mountConfig.CSIPodServiceAccountTokens = `{"infisical":{"token":"test-token"}}`
+				mockInfisicalClient.EXPECT().KubernetesAuthLogin("test-identity", "test-token")
+
+				// When
+				_, err := auth.NewAuth(kubeClient).Login(ctx, mockInfisicalClient, mountConfig)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithKubernetesAuthWithoutServiceAccountToken",
+			func(t *testing.T) {
+				// Given
+				mountConfig.AuthMethod = config.AuthMethodKubernetes
+				mountConfig.IdentityID = "test-identity"
+
+				// When
+				_, err := auth.NewAuth(kubeClient).Login(ctx, mockInfisicalClient, mountConfig)
+
+				// Then
+				var credentialsErr *auth.CredentialsError
+				if !errors.As(err, &credentialsErr) {
+					t.Errorf("unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithOIDCAuth",
+			func(t *testing.T) {
+				// Given
+				mountConfig.AuthMethod = config.AuthMethodOIDC
+				mountConfig.IdentityID = "test-identity"
+				mountConfig.CSIPodServiceAccountTokens = `{"infisical":{"token":"test-token"}}`
+				mockInfisicalClient.EXPECT().OidcAuthLogin("test-identity", "test-token")
+
+				// When
+				_, err := auth.NewAuth(kubeClient).Login(ctx, mockInfisicalClient, mountConfig)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithAWSIAMAuth",
+			func(t *testing.T) {
+				// Given
+				mountConfig.AuthMethod = config.AuthMethodAWSIAM
+				mountConfig.IdentityID = "test-identity"
+				mockInfisicalClient.EXPECT().AwsIamAuthLogin("test-identity")
+
+				// When
+				_, err := auth.NewAuth(kubeClient).Login(ctx, mockInfisicalClient, mountConfig)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithGCPIDTokenAuth",
+			func(t *testing.T) {
+				// Given
+				mountConfig.AuthMethod = config.AuthMethodGCPIDToken
+				mountConfig.IdentityID = "test-identity"
+				mockInfisicalClient.EXPECT().GcpIdTokenAuthLogin("test-identity")
+
+				// When
+				_, err := auth.NewAuth(kubeClient).Login(ctx, mockInfisicalClient, mountConfig)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithAzureAuth",
+			func(t *testing.T) {
+				// Given
+				mountConfig.AuthMethod = config.AuthMethodAzure
+				mountConfig.IdentityID = "test-identity"
+				mountConfig.AzureResource = "https://example.com/"
+				mockInfisicalClient.EXPECT().AzureAuthLogin("test-identity", "https://example.com/")
+
+				// When
+				_, err := auth.NewAuth(kubeClient).Login(ctx, mockInfisicalClient, mountConfig)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+	} {
+		ctx = context.Background()
+		ctrl := gomock.NewController(t)
+		mockInfisicalClient = mock_provider.NewMockInfisicalClient(ctrl)
+		kubeClient = fake.NewSimpleClientset(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "test-namespace",
+				Name:      "test-infisical-credentials",
+			},
+			Data: map[string][]byte{
+				"client-id":     []byte("test-client-id"),
+				"client-secret": []byte("test-client-secret"),
+			},
+		})
+		mountConfig = config.NewMountConfig(*config.NewValidator())
+		mountConfig.AuthSecretName = "test-infisical-credentials"
+		mountConfig.AuthSecretNamespace = "test-namespace"
+
+		t.Run(testcase.name, testcase.f)
+	}
+}
+
+func TestAuthIdentities(t *testing.T) {
+	var (
+		ctx                 context.Context
+		kubeClient          *fake.Clientset
+		mockInfisicalClient *mock_provider.MockInfisicalClient
+		mountConfig         *config.MountConfig
+	)
+
+	for _, testcase := range []struct {
+		name string
+		f    func(t *testing.T)
+	}{
+		{
+			"SuccessfullyWithUniversalAuthSharedByKubeSecretsWithSameCredentials",
+			func(t *testing.T) {
+				// Given
+				_, _ = kubeClient.CoreV1().Secrets("another-namespace").Create(ctx, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "another-namespace", Name: "copied"},
+					Data: map[string][]byte{
+						"client-id":     []byte("test-client-id"),
+						"client-secret": []byte("test-client-secret"),
+					},
+				}, metav1.CreateOptions{})
+				a := auth.NewAuth(kubeClient)
+				expected, _ := a.Login(ctx, mockInfisicalClient, mountConfig)
+				mountConfig.AuthSecretNamespace = "another-namespace"
+				mountConfig.AuthSecretName = "copied"
+
+				// When
+				actual, err := a.Login(ctx, mockInfisicalClient, mountConfig)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if actual != expected {
+					t.Errorf("unexpected identity: %s", actual)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithUniversalAuthDifferentFromAnotherClientSecret",
+			func(t *testing.T) {
+				// Given
+				_, _ = kubeClient.CoreV1().Secrets("test-namespace").Create(ctx, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "test-namespace", Name: "another"},
+					Data: map[string][]byte{
+						"client-id":     []byte("test-client-id"),
+						"client-secret": []byte("another-client-secret"),
+					},
+				}, metav1.CreateOptions{})
+				a := auth.NewAuth(kubeClient)
+				other, _ := a.Login(ctx, mockInfisicalClient, mountConfig)
+				mountConfig.AuthSecretName = "another"
+
+				// When
+				actual, err := a.Login(ctx, mockInfisicalClient, mountConfig)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if actual == other {
+					t.Errorf("identity shared with another client secret: %s", actual)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithKubernetesAuthDifferentFromAnotherServiceAccount",
+			func(t *testing.T) {
+				// Given
+				a := auth.NewAuth(kubeClient)
+				mountConfig.AuthMethod = config.AuthMethodKubernetes
+				mountConfig.IdentityID = "test-identity"
+				mountConfig.CSIPodServiceAccountTokens = `{"infisical":{"token":"test-token"}}`
+				mountConfig.CSIPodNamespace = "test-namespace"
+				mountConfig.CSIPodServiceAccountName = "test-service-account"
+				other, _ := a.Login(ctx, mockInfisicalClient, mountConfig)
+				mountConfig.CSIPodServiceAccountName = "another-service-account"
+
+				// When
+				actual, err := a.Login(ctx, mockInfisicalClient, mountConfig)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if actual == other {
+					t.Errorf("identity shared with another service account: %s", actual)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithKubernetesAuthWithoutServiceAccount",
+			func(t *testing.T) {
+				// Given
+				mountConfig.AuthMethod = config.AuthMethodKubernetes
+				mountConfig.IdentityID = "test-identity"
+				mountConfig.CSIPodServiceAccountTokens = `{"infisical":{"token":"test-token"}}`
+
+				// When
+				actual, err := auth.NewAuth(kubeClient).Login(ctx, mockInfisicalClient, mountConfig)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if actual != "" {
+					t.Errorf("unexpected identity: %s", actual)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithUniversalAuthWhenLoginFailed",
+			func(t *testing.T) {
+				// Given
+				a := auth.NewAuth(kubeClient)
+				expected, _ := a.Login(ctx, mockInfisicalClient, mountConfig)
+				loginErr := errors.New("failed to login")
+				failingClient := mock_provider.NewMockInfisicalClient(gomock.NewController(t))
+				failingClient.EXPECT().UniversalAuthLogin("test-client-id", "test-client-secret").Return(infisical.MachineIdentityCredential{}, loginErr)
+				a = auth.NewAuth(kubeClient)
+
+				// When
+				actual, err := a.Login(ctx, failingClient, mountConfig)
+
+				// Then
+				if !errors.Is(err, loginErr) {
+					t.Errorf("unexpected error: %v", err)
+				}
+				if actual != expected {
+					t.Errorf("unexpected identity: %s", actual)
+				}
+			},
+		},
+		{
+			"FailedWithUnregisteredStrategy",
+			func(t *testing.T) {
+				// Given
+				mountConfig.AuthMethod = "test"
+
+				// When
+				_, err := auth.NewAuth(kubeClient).Login(ctx, mockInfisicalClient, mountConfig)
+
+				// Then
+				var credentialsErr *auth.CredentialsError
+				if !errors.As(err, &credentialsErr) {
+					t.Errorf("unexpected error: %v", err)
+				}
+			},
+		},
+	} {
+		ctx = context.Background()
+		mockInfisicalClient = mock_provider.NewMockInfisicalClient(gomock.NewController(t))
+		mockInfisicalClient.EXPECT().UniversalAuthLogin(gomock.Any(), gomock.Any()).AnyTimes()
+		mockInfisicalClient.EXPECT().KubernetesAuthLogin(gomock.Any(), gomock.Any()).AnyTimes()
+		kubeClient = fake.NewSimpleClientset(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "test-namespace",
+				Name:      "test-infisical-credentials",
+			},
+			Data: map[string][]byte{
+				"client-id":     []byte("test-client-id"),
+				"client-secret": []byte("test-client-secret"),
+			},
+		})
+		mountConfig = config.NewMountConfig(*config.NewValidator())
+		mountConfig.AuthSecretName = "test-infisical-credentials"
+		mountConfig.AuthSecretNamespace = "test-namespace"
+
+		t.Run(testcase.name, testcase.f)
+	}
+}
+
+func TestUniversalAuthTokenCache(t *testing.T) {
+	var (
+		ctx                 context.Context
+		kubeClient          *fake.Clientset
+		mockInfisicalClient *mock_provider.MockInfisicalClient
+		mountConfig         *config.MountConfig
+		current             time.Time
+		credential          infisical.MachineIdentityCredential
+	)
+
+	for _, testcase := range []struct {
+		name string
+		f    func(t *testing.T)
+	}{
+		{
+			"SuccessfullyWithCachedToken",
+			func(t *testing.T) {
+				// Given
+				a := auth.NewAuth(kubeClient)
+				mockInfisicalClient.EXPECT().UniversalAuthLogin("test-client-id", "test-client-secret").Return(credential, nil)
+				_, _ = a.Login(ctx, mockInfisicalClient, mountConfig)
+				current = current.Add(79 * time.Second)
+				mockInfisicalClient.EXPECT().SetAccessToken("test-access-token")
+
+				// When
+				_, err := a.Login(ctx, mockInfisicalClient, mountConfig)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithRenewalBeforeExpiration",
+			func(t *testing.T) {
+				// Given
+				a := auth.NewAuth(kubeClient)
+				mockInfisicalClient.EXPECT().UniversalAuthLogin("test-client-id", "test-client-secret").Return(credential, nil)
+				_, _ = a.Login(ctx, mockInfisicalClient, mountConfig)
+				current = current.Add(80 * time.Second)
+				renewed := credential
+				renewed.AccessToken = "test-renewed-access-token"
+				mockInfisicalClient.EXPECT().RenewAccessToken("test-access-token").Return(renewed, nil)
+
+				// When
+				_, err := a.Login(ctx, mockInfisicalClient, mountConfig)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				mockInfisicalClient.EXPECT().SetAccessToken("test-renewed-access-token")
+				_, _ = a.Login(ctx, mockInfisicalClient, mountConfig)
+			},
+		},
+		{
+			"SuccessfullyWithLoginAfterRenewalFailure",
+			func(t *testing.T) {
+				// Given
+				a := auth.NewAuth(kubeClient)
+				mockInfisicalClient.EXPECT().UniversalAuthLogin("test-client-id", "test-client-secret").Return(credential, nil).Times(2)
+				_, _ = a.Login(ctx, mockInfisicalClient, mountConfig)
+				current = current.Add(80 * time.Second)
+				mockInfisicalClient.EXPECT().RenewAccessToken("test-access-token").Return(infisical.MachineIdentityCredential{}, errors.New("failed to renew"))
+
+				// When
+				_, err := a.Login(ctx, mockInfisicalClient, mountConfig)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithLoginNearMaxTTL",
+			func(t *testing.T) {
+				// Given
+				a := auth.NewAuth(kubeClient)
+				mockInfisicalClient.EXPECT().UniversalAuthLogin("test-client-id", "test-client-secret").Return(credential, nil).Times(2)
+				_, _ = a.Login(ctx, mockInfisicalClient, mountConfig)
+				current = current.Add(800 * time.Second)
+
+				// When
+				_, err := a.Login(ctx, mockInfisicalClient, mountConfig)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithLoginAfterExpiration",
+			func(t *testing.T) {
+				// Given
+				a := auth.NewAuth(kubeClient)
+				mockInfisicalClient.EXPECT().UniversalAuthLogin("test-client-id", "test-client-secret").Return(credential, nil).Times(2)
+				_, _ = a.Login(ctx, mockInfisicalClient, mountConfig)
+				current = current.Add(100 * time.Second)
+
+				// When
+				_, err := a.Login(ctx, mockInfisicalClient, mountConfig)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithLoginAfterKubeSecretUpdate",
+			func(t *testing.T) {
+				// Given
+				a := auth.NewAuth(kubeClient)
+				mockInfisicalClient.EXPECT().UniversalAuthLogin("test-client-id", "test-client-secret").Return(credential, nil)
+				_, _ = a.Login(ctx, mockInfisicalClient, mountConfig)
+				_, _ = kubeClient.CoreV1().Secrets("test-namespace").Update(ctx, &corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{Namespace: "test-namespace", Name: "test-infisical-credentials", ResourceVersion: "2"},
+					Data: map[string][]byte{
+						"client-id":     []byte("test-client-id"),
+						"client-secret": []byte("test-new-client-secret"),
+					},
+				}, metav1.UpdateOptions{})
+				mockInfisicalClient.EXPECT().UniversalAuthLogin("test-client-id", "test-new-client-secret").Return(credential, nil)
+
+				// When
+				_, err := a.Login(ctx, mockInfisicalClient, mountConfig)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithLoginAfterInvalidation",
+			func(t *testing.T) {
+				// Given
+				a := auth.NewAuth(kubeClient)
+				mockInfisicalClient.EXPECT().UniversalAuthLogin("test-client-id", "test-client-secret").Return(credential, nil).Times(2)
+				_, _ = a.Login(ctx, mockInfisicalClient, mountConfig)
+				a.Invalidate(mountConfig)
+
+				// When
+				_, err := a.Login(ctx, mockInfisicalClient, mountConfig)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWhenMountStartingLoginLeaves",
+			func(t *testing.T) {
+				// Given
+				a := auth.NewAuth(kubeClient)
+				leavingCtx, cancel := context.WithCancel(ctx)
+				leavingInfisicalClient := mock_provider.NewMockInfisicalClient(gomock.NewController(t))
+				leavingInfisicalClient.EXPECT().UniversalAuthLogin("test-client-id", "test-client-secret").DoAndReturn(func(string, string) (infisical.MachineIdentityCredential, error) {
+					<-leavingCtx.Done()
+					return infisical.MachineIdentityCredential{}, leavingCtx.Err()
+				})
+				mockInfisicalClient.EXPECT().UniversalAuthLogin("test-client-id", "test-client-secret").Return(credential, nil)
+				left := make(chan error)
+				go func() {
+					_, err := a.Login(leavingCtx, leavingInfisicalClient, mountConfig)
+					left <- err
+				}()
+				time.Sleep(50 * time.Millisecond)
+				loggedIn := make(chan struct{})
+				var err error
+
+				// When
+				go func() {
+					defer close(loggedIn)
+					_, err = a.Login(ctx, mockInfisicalClient, mountConfig)
+				}()
+				time.Sleep(50 * time.Millisecond)
+				cancel()
+				if err := <-left; !errors.Is(err, context.Canceled) {
+					t.Errorf("unexpected error of the mount leaving: %v", err)
+				}
+				<-loggedIn
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithoutCachingAcrossSites",
+			func(t *testing.T) {
+				// Given
+				a := auth.NewAuth(kubeClient)
+				mockInfisicalClient.EXPECT().UniversalAuthLogin("test-client-id", "test-client-secret").Return(credential, nil).Times(2)
+				_, _ = a.Login(ctx, mockInfisicalClient, mountConfig)
+				mountConfig.SiteUrl = "https://infisical.example.com"
+
+				// When
+				_, err := a.Login(ctx, mockInfisicalClient, mountConfig)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+	} {
+		ctx = context.Background()
+		ctrl := gomock.NewController(t)
+		mockInfisicalClient = mock_provider.NewMockInfisicalClient(ctrl)
+		kubeClient = fake.NewSimpleClientset(&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:       "test-namespace",
+				Name:            "test-infisical-credentials",
+				ResourceVersion: "1",
+			},
+			Data: map[string][]byte{
+				"client-id":     []byte("test-client-id"),
+				"client-secret": []byte("test-client-secret"),
+			},
+		})
+		mountConfig = config.NewMountConfig(*config.NewValidator())
+		mountConfig.AuthSecretName = "test-infisical-credentials"
+		mountConfig.AuthSecretNamespace = "test-namespace"
+		current = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		credential = infisical.MachineIdentityCredential{
+			AccessToken:       "test-access-token",
+			ExpiresIn:         100,
+			AccessTokenMaxTTL: 1000,
+		}
+		restore := auth.SetNow(func() time.Time { return current })
+
+		t.Run(testcase.name, testcase.f)
+
+		restore()
+	}
+}
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	infisical "github.com/infisical/go-sdk"
+	"golang.org/x/sync/singleflight"
+)
+
+// tokenRefreshRatio is the ratio of the TTL after which a cached token is refreshed.
+// The same ratio is used by the kubelet to rotate projected service account tokens.
+const tokenRefreshRatio = 0.8
+
+type token struct {
+	credential infisical.MachineIdentityCredential
+	// version is the version of the credentials the token is obtained with
+	version string
+	// loggedInAt is when the token is issued, which limits the renewals by AccessTokenMaxTTL
+	loggedInAt time.Time
+	// renewedAt is when the token is issued or last renewed, which starts ExpiresIn
+	renewedAt time.Time
+}
+
+func (t *token) fresh(now time.Time) bool {
+	ttl := time.Duration(float64(t.credential.ExpiresIn) * tokenRefreshRatio * float64(time.Second))
+	return now.Before(t.renewedAt.Add(ttl))
+}
+
+// expired reports whether the token is no longer accepted, so that it can be neither used nor renewed.
+func (t *token) expired(now time.Time) bool {
+	return !now.Before(t.renewedAt.Add(time.Duration(t.credential.ExpiresIn) * time.Second))
+}
+
+// renewable reports whether a renewed token would be valid for a while.
+// A token whose AccessTokenMaxTTL is not positive is never renewed.
+func (t *token) renewable(now time.Time) bool {
+	if t.credential.AccessTokenMaxTTL <= 0 {
+		return false
+	}
+	maxTTL := time.Duration(float64(t.credential.AccessTokenMaxTTL) * tokenRefreshRatio * float64(time.Second))
+	return now.Before(t.loggedInAt.Add(maxTTL))
+}
+
+// tokenCache holds Infisical access tokens to be reused across mounts.
+// Tokens are evicted once they expire or the credentials they are obtained with are updated.
+// Concurrent logins missing the cache with the same identity share one login.
+type tokenCache struct {
+	mu     sync.Mutex
+	tokens map[string]*token
+	logins singleflight.Group
+}
+
+func newTokenCache() *tokenCache {
+	return &tokenCache{
+		tokens: map[string]*token{},
+	}
+}
+
+// get returns the token cached for the key if it is obtained with the version of the credentials.
+func (c *tokenCache) get(key, version string) (*token, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sweep()
+	t, ok := c.tokens[key]
+	if !ok {
+		return nil, false
+	}
+	if t.version != version {
+		delete(c.tokens, key)
+		return nil, false
+	}
+	copied := *t
+	return &copied, true
+}
+
+func (c *tokenCache) set(key string, t *token) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sweep()
+	c.tokens[key] = t
+}
+
+func (c *tokenCache) delete(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.tokens, key)
+}
+
+// login calls login, sharing the call with the concurrent ones of the identity, and returns the error of ctx when it is done first.
+// shared reports whether the credential is obtained by another call, whose client is not logged in.
+// A shared call failing since the context of another caller is done is made again while ctx is not done.
+func (c *tokenCache) login(ctx context.Context, identity string, login func() (infisical.MachineIdentityCredential, error)) (credential infisical.MachineIdentityCredential, shared bool, err error) {
+	for {
+		called := false
+		var result singleflight.Result
+		select {
+		case result = <-c.logins.DoChan(identity, func() (any, error) {
+			called = true
+			return login()
+		}):
+		case <-ctx.Done():
+			// the call is left to the other callers, or fails soon with ctx as the login of the client does
+			return infisical.MachineIdentityCredential{}, true, ctx.Err()
+		}
+		if result.Err != nil {
+			if !called && ctx.Err() == nil && (errors.Is(result.Err, context.Canceled) || errors.Is(result.Err, context.DeadlineExceeded)) {
+				continue
+			}
+			return infisical.MachineIdentityCredential{}, !called, result.Err
+		}
+		return result.Val.(infisical.MachineIdentityCredential), !called, nil
+	}
+}
+
+// sweep evicts expired tokens, including the ones of Kubernetes Secrets no longer mounted.
+func (c *tokenCache) sweep() {
+	current := now()
+	for key, t := range c.tokens {
+		if t.expired(current) {
+			delete(c.tokens, key)
+		}
+	}
+}
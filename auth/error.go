@@ -0,0 +1,21 @@
+package auth
+
+// CredentialsError is returned when the credentials to log in with cannot be obtained.
+// Other errors returned by Login are failures of the login itself.
+type CredentialsError struct {
+	Err error
+}
+
+func NewCredentialsError(err error) *CredentialsError {
+	return &CredentialsError{
+		Err: err,
+	}
+}
+
+func (e *CredentialsError) Error() string {
+	return "failed to get credentials: " + e.Err.Error()
+}
+
+func (e *CredentialsError) Unwrap() error {
+	return e.Err
+}
@@ -13,11 +13,49 @@ import (
 	context "context"
 	reflect "reflect"
 
-	auth "github.com/gidoichi/secrets-store-csi-driver-provider-infisical/auth"
+	config "github.com/gidoichi/secrets-store-csi-driver-provider-infisical/config"
+	provider "github.com/gidoichi/secrets-store-csi-driver-provider-infisical/provider"
 	gomock "go.uber.org/mock/gomock"
-	types "k8s.io/apimachinery/pkg/types"
 )
 
+// MockStrategy is a mock of Strategy interface.
+type MockStrategy struct {
+	ctrl     *gomock.Controller
+	recorder *MockStrategyMockRecorder
+}
+
+// MockStrategyMockRecorder is the mock recorder for MockStrategy.
+type MockStrategyMockRecorder struct {
+	mock *MockStrategy
+}
+
+// NewMockStrategy creates a new mock instance.
+func NewMockStrategy(ctrl *gomock.Controller) *MockStrategy {
+	mock := &MockStrategy{ctrl: ctrl}
+	mock.recorder = &MockStrategyMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStrategy) EXPECT() *MockStrategyMockRecorder {
+	return m.recorder
+}
+
+// Login mocks base method.
+func (m *MockStrategy) Login(ctx context.Context, client provider.InfisicalClient, mountConfig *config.MountConfig) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Login", ctx, client, mountConfig)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Login indicates an expected call of Login.
+func (mr *MockStrategyMockRecorder) Login(ctx, client, mountConfig any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockStrategy)(nil).Login), ctx, client, mountConfig)
+}
+
 // MockAuth is a mock of Auth interface.
 type MockAuth struct {
 	ctrl     *gomock.Controller
@@ -41,17 +79,64 @@ func (m *MockAuth) EXPECT() *MockAuthMockRecorder {
 	return m.recorder
 }
 
-// TokenFromKubeSecret mocks base method.
-func (m *MockAuth) TokenFromKubeSecret(ctx context.Context, secretRef types.NamespacedName) (*auth.Credentials, error) {
+// Invalidate mocks base method.
+func (m *MockAuth) Invalidate(mountConfig *config.MountConfig) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Invalidate", mountConfig)
+}
+
+// Invalidate indicates an expected call of Invalidate.
+func (mr *MockAuthMockRecorder) Invalidate(mountConfig any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Invalidate", reflect.TypeOf((*MockAuth)(nil).Invalidate), mountConfig)
+}
+
+// Login mocks base method.
+func (m *MockAuth) Login(ctx context.Context, client provider.InfisicalClient, mountConfig *config.MountConfig) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "TokenFromKubeSecret", ctx, secretRef)
-	ret0, _ := ret[0].(*auth.Credentials)
+	ret := m.ctrl.Call(m, "Login", ctx, client, mountConfig)
+	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// TokenFromKubeSecret indicates an expected call of TokenFromKubeSecret.
-func (mr *MockAuthMockRecorder) TokenFromKubeSecret(ctx, secretRef any) *gomock.Call {
+// Login indicates an expected call of Login.
+func (mr *MockAuthMockRecorder) Login(ctx, client, mountConfig any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockAuth)(nil).Login), ctx, client, mountConfig)
+}
+
+// Mockinvalidator is a mock of invalidator interface.
+type Mockinvalidator struct {
+	ctrl     *gomock.Controller
+	recorder *MockinvalidatorMockRecorder
+}
+
+// MockinvalidatorMockRecorder is the mock recorder for Mockinvalidator.
+type MockinvalidatorMockRecorder struct {
+	mock *Mockinvalidator
+}
+
+// NewMockinvalidator creates a new mock instance.
+func NewMockinvalidator(ctrl *gomock.Controller) *Mockinvalidator {
+	mock := &Mockinvalidator{ctrl: ctrl}
+	mock.recorder = &MockinvalidatorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *Mockinvalidator) EXPECT() *MockinvalidatorMockRecorder {
+	return m.recorder
+}
+
+// Invalidate mocks base method.
+func (m *Mockinvalidator) Invalidate(mountConfig *config.MountConfig) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Invalidate", mountConfig)
+}
+
+// Invalidate indicates an expected call of Invalidate.
+func (mr *MockinvalidatorMockRecorder) Invalidate(mountConfig any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TokenFromKubeSecret", reflect.TypeOf((*MockAuth)(nil).TokenFromKubeSecret), ctx, secretRef)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Invalidate", reflect.TypeOf((*Mockinvalidator)(nil).Invalidate), mountConfig)
 }
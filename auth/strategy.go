@@ -0,0 +1,215 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/config"
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/metrics"
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/provider"
+	infisical "github.com/infisical/go-sdk"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+var (
+	idKey     = "client-id"
+	secretKey = "client-secret"
+	tokenKey  = "access-token"
+
+	now = time.Now
+)
+
+type Credentials struct {
+	ID          string
+	Secret      string
+	AccessToken string
+}
+
+// universalAuth logs in with the client ID and the client secret stored in a Kubernetes Secret.
+// A Kubernetes Secret containing only an access token is also accepted, and the token is used as it is.
+// Obtained tokens are cached per Kubernetes Secret and refreshed before they expire.
+type universalAuth struct {
+	kubeClient kubernetes.Interface
+	tokens     *tokenCache
+}
+
+func (a *universalAuth) Login(ctx context.Context, client provider.InfisicalClient, mountConfig *config.MountConfig) (string, error) {
+	credentials, version, err := credentialsFromKubeSecret(ctx, a.kubeClient, mountConfig)
+	if err != nil {
+		return "", NewCredentialsError(err)
+	}
+
+	if credentials.ID == "" && credentials.Secret == "" && credentials.AccessToken != "" {
+		client.SetAccessToken(credentials.AccessToken)
+		return identity(config.AuthMethodAccessToken, credentials.AccessToken), nil
+	}
+	if credentials.ID == "" {
+		return "", NewCredentialsError(fmt.Errorf("%s not found in secret %s/%s", idKey, mountConfig.AuthSecretNamespace, mountConfig.AuthSecretName))
+	}
+	if credentials.Secret == "" {
+		return "", NewCredentialsError(fmt.Errorf("%s not found in secret %s/%s", secretKey, mountConfig.AuthSecretNamespace, mountConfig.AuthSecretName))
+	}
+	id := identity(config.AuthMethodUniversalAuth, credentials.ID, credentials.Secret)
+
+	key := kubeSecretCacheKey(mountConfig)
+	current := now()
+	if cached, ok := a.tokens.get(key, version); ok {
+		if cached.fresh(current) {
+			metrics.TokenCacheLookups.WithLabelValues(metrics.CacheHit).Inc()
+			client.SetAccessToken(cached.credential.AccessToken)
+			return id, nil
+		}
+		if cached.renewable(current) {
+			// log in again when the renewal failed
+			if credential, err := client.RenewAccessToken(cached.credential.AccessToken); err == nil {
+				metrics.TokenCacheLookups.WithLabelValues(metrics.CacheRenewed).Inc()
+				cached.credential = credential
+				cached.renewedAt = current
+				a.tokens.set(key, cached)
+				return id, nil
+			}
+		}
+	}
+
+	credential, shared, err := a.tokens.login(ctx, mountConfig.SiteUrl+" "+id, func() (infisical.MachineIdentityCredential, error) {
+		return client.UniversalAuthLogin(credentials.ID, credentials.Secret)
+	})
+	if shared {
+		metrics.TokenCacheLookups.WithLabelValues(metrics.CacheShared).Inc()
+	} else {
+		metrics.TokenCacheLookups.WithLabelValues(metrics.CacheMiss).Inc()
+	}
+	if err != nil {
+		a.tokens.delete(key)
+		return id, err
+	}
+	if shared {
+		client.SetAccessToken(credential.AccessToken)
+	}
+	a.tokens.set(key, &token{
+		credential: credential,
+		version:    version,
+		loggedInAt: current,
+		renewedAt:  current,
+	})
+	return id, nil
+}
+
+func (a *universalAuth) Invalidate(mountConfig *config.MountConfig) {
+	a.tokens.delete(kubeSecretCacheKey(mountConfig))
+}
+
+// accessToken uses the access token stored in a Kubernetes Secret as it is.
+type accessToken struct {
+	kubeClient kubernetes.Interface
+}
+
+func (a *accessToken) Login(ctx context.Context, client provider.InfisicalClient, mountConfig *config.MountConfig) (string, error) {
+	credentials, _, err := credentialsFromKubeSecret(ctx, a.kubeClient, mountConfig)
+	if err != nil {
+		return "", NewCredentialsError(err)
+	}
+
+	if credentials.AccessToken == "" {
+		return "", NewCredentialsError(fmt.Errorf("%s not found in secret %s/%s", tokenKey, mountConfig.AuthSecretNamespace, mountConfig.AuthSecretName))
+	}
+
+	client.SetAccessToken(credentials.AccessToken)
+	return identity(config.AuthMethodAccessToken, credentials.AccessToken), nil
+}
+
+// kubernetesAuth logs in with the service account token of the mounting pod.
+type kubernetesAuth struct{}
+
+func (a *kubernetesAuth) Login(_ context.Context, client provider.InfisicalClient, mountConfig *config.MountConfig) (string, error) {
+	token, err := mountConfig.ServiceAccountToken()
+	if err != nil {
+		return "", NewCredentialsError(err)
+	}
+
+	_, err = client.KubernetesAuthLogin(mountConfig.IdentityID, token)
+	return serviceAccountIdentity(config.AuthMethodKubernetes, mountConfig), err
+}
+
+// oidcAuth logs in with the service account token of the mounting pod as an OIDC ID token.
+type oidcAuth struct{}
+
+func (a *oidcAuth) Login(_ context.Context, client provider.InfisicalClient, mountConfig *config.MountConfig) (string, error) {
+	token, err := mountConfig.ServiceAccountToken()
+	if err != nil {
+		return "", NewCredentialsError(err)
+	}
+
+	_, err = client.OidcAuthLogin(mountConfig.IdentityID, token)
+	return serviceAccountIdentity(config.AuthMethodOIDC, mountConfig), err
+}
+
+// awsIAMAuth logs in with the AWS IAM role of the provider.
+type awsIAMAuth struct{}
+
+func (a *awsIAMAuth) Login(_ context.Context, client provider.InfisicalClient, mountConfig *config.MountConfig) (string, error) {
+	_, err := client.AwsIamAuthLogin(mountConfig.IdentityID)
+	return identity(config.AuthMethodAWSIAM, mountConfig.IdentityID), err
+}
+
+// gcpIDTokenAuth logs in with the ID token of the GCP service account of the provider.
+type gcpIDTokenAuth struct{}
+
+func (a *gcpIDTokenAuth) Login(_ context.Context, client provider.InfisicalClient, mountConfig *config.MountConfig) (string, error) {
+	_, err := client.GcpIdTokenAuthLogin(mountConfig.IdentityID)
+	return identity(config.AuthMethodGCPIDToken, mountConfig.IdentityID), err
+}
+
+// azureAuth logs in with the Azure managed identity of the provider.
+type azureAuth struct{}
+
+func (a *azureAuth) Login(_ context.Context, client provider.InfisicalClient, mountConfig *config.MountConfig) (string, error) {
+	_, err := client.AzureAuthLogin(mountConfig.IdentityID, mountConfig.AzureResource)
+	return identity(config.AuthMethodAzure, mountConfig.IdentityID, mountConfig.AzureResource), err
+}
+
+// credentialsFromKubeSecret returns the credentials stored in the Kubernetes Secret and the resourceVersion of the Secret.
+func credentialsFromKubeSecret(ctx context.Context, kubeClient kubernetes.Interface, mountConfig *config.MountConfig) (*Credentials, string, error) {
+	secretRef := types.NamespacedName{
+		Namespace: mountConfig.AuthSecretNamespace,
+		Name:      mountConfig.AuthSecretName,
+	}
+	secret, err := kubeClient.CoreV1().Secrets(secretRef.Namespace).Get(ctx, secretRef.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &Credentials{
+		ID:          string(secret.Data[idKey]),
+		Secret:      string(secret.Data[secretKey]),
+		AccessToken: string(secret.Data[tokenKey]),
+	}, secret.ResourceVersion, nil
+}
+
+func kubeSecretCacheKey(mountConfig *config.MountConfig) string {
+	return mountConfig.SiteUrl + " " + mountConfig.AuthSecretNamespace + "/" + mountConfig.AuthSecretName
+}
+
+// identity returns the digest of the auth method and the credentials.
+func identity(method string, credentials ...string) string {
+	digest := sha256.New()
+	for _, field := range append([]string{method}, credentials...) {
+		digest.Write([]byte(field))
+		digest.Write([]byte{0})
+	}
+	return hex.EncodeToString(digest.Sum(nil))
+}
+
+// serviceAccountIdentity returns the identity of the Infisical identity logged in with the service account of the mounting pod,
+// or an empty identity when the CSI driver does not pass the service account.
+func serviceAccountIdentity(method string, mountConfig *config.MountConfig) string {
+	if mountConfig.CSIPodNamespace == "" || mountConfig.CSIPodServiceAccountName == "" {
+		return ""
+	}
+	return identity(method, mountConfig.IdentityID, mountConfig.CSIPodNamespace, mountConfig.CSIPodServiceAccountName, mountConfig.ServiceAccountTokenAudience)
+}
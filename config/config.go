@@ -1,36 +1,83 @@
 package config
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"path"
 	"reflect"
+	"slices"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"gopkg.in/yaml.v3"
 )
 
+const (
+	// TagMatchAny selects secrets having any of the tags.
+	TagMatchAny = "any"
+	// TagMatchAll selects secrets having all of the tags.
+	TagMatchAll = "all"
+)
+
+const (
+	// ExpandReferencesTrue expands references by the provider.
+	ExpandReferencesTrue = "true"
+	// ExpandReferencesFalse mounts values with references as they are.
+	ExpandReferencesFalse = "false"
+	// ExpandReferencesServer expands references by Infisical.
+	ExpandReferencesServer = "server"
+)
+
+const (
+	AuthMethodUniversalAuth = "universal-auth"
+	AuthMethodAccessToken   = "access-token"
+	AuthMethodKubernetes    = "kubernetes"
+	AuthMethodOIDC          = "oidc"
+	AuthMethodAWSIAM        = "aws-iam"
+	AuthMethodGCPIDToken    = "gcp-id-token"
+	AuthMethodAzure         = "azure"
+)
+
 type MountConfig struct {
-	Project                  string  `json:"projectSlug" validate:"required"`
-	Env                      string  `json:"envSlug" validate:"required"`
-	Path                     string  `json:"secretsPath" validate:"required"`
-	AuthSecretName           string  `json:"authSecretName" validate:"required"`
-	AuthSecretNamespace      string  `json:"authSecretNamespace" validate:"required"`
-	RawObjects               *string `json:"objects"`
-	CSIPodName               string  `json:"csi.storage.k8s.io/pod.name"`
-	CSIPodNamespace          string  `json:"csi.storage.k8s.io/pod.namespace"`
-	CSIPodUID                string  `json:"csi.storage.k8s.io/pod.uid"`
-	CSIPodServiceAccountName string  `json:"csi.storage.k8s.io/serviceAccount.name"`
-	CSIEphemeral             string  `json:"csi.storage.k8s.io/ephemeral"`
-	SecretProviderClass      string  `json:"secretProviderClass"`
-	parsedObjects            []object
-	validator                validator.Validate
-}
-
-type object struct {
-	Name  string `yaml:"objectName" validate:"required"`
-	Alias string `yaml:"objectAlias" validate:"excludes=/"`
+	Project                     string  `json:"projectSlug" validate:"required"`
+	Env                         string  `json:"envSlug" validate:"required"`
+	Path                        string  `json:"secretsPath" validate:"required"`
+	Recursive                   bool    `json:"recursive,string"`
+	IncludeImports              bool    `json:"includeImports,string"`
+	ExpandReferences            string  `json:"expandReferences" validate:"oneof=true false server"`
+	StrictReferences            bool    `json:"strictReferences,string"`
+	MaxReferenceDepth           int     `json:"maxReferenceDepth,string" validate:"gte=0"`
+	EscapeReferences            bool    `json:"escapeReferences,string"`
+	TagSlugs                    string  `json:"tagSlugs"`
+	TagMatch                    string  `json:"tagMatch" validate:"oneof=any all"`
+	SiteUrl                     string  `json:"siteUrl" validate:"omitempty,url,startswith=https://"`
+	AuthMethod                  string  `json:"authMethod" validate:"oneof=universal-auth access-token kubernetes oidc aws-iam gcp-id-token azure"`
+	AuthSecretName              string  `json:"authSecretName" validate:"required_if=AuthMethod universal-auth,required_if=AuthMethod access-token"`
+	AuthSecretNamespace         string  `json:"authSecretNamespace" validate:"required_if=AuthMethod universal-auth,required_if=AuthMethod access-token"`
+	IdentityID                  string  `json:"identityId" validate:"required_if=AuthMethod kubernetes,required_if=AuthMethod oidc,required_if=AuthMethod aws-iam,required_if=AuthMethod gcp-id-token,required_if=AuthMethod azure"`
+	ServiceAccountTokenAudience string  `json:"serviceAccountTokenAudience"`
+	AzureResource               string  `json:"azureResource" validate:"excluded_unless=AuthMethod azure"`
+	RawObjects                  *string `json:"objects"`
+	RawTemplates                *string `json:"templates"`
+	CSIPodName                  string  `json:"csi.storage.k8s.io/pod.name"`
+	CSIPodNamespace             string  `json:"csi.storage.k8s.io/pod.namespace"`
+	CSIPodUID                   string  `json:"csi.storage.k8s.io/pod.uid"`
+	CSIPodServiceAccountName    string  `json:"csi.storage.k8s.io/serviceAccount.name"`
+	CSIPodServiceAccountTokens  string  `json:"csi.storage.k8s.io/serviceAccount.tokens"`
+	CSIEphemeral                string  `json:"csi.storage.k8s.io/ephemeral"`
+	SecretProviderClass         string  `json:"secretProviderClass"`
+	parsedObjects               []Object
+	parsedTemplates             []FileTemplate
+	validator                   validator.Validate
+}
+
+// serviceAccountToken is an entry of the csi.storage.k8s.io/serviceAccount.tokens attribute.
+// c.f. https://kubernetes-csi.github.io/docs/token-requests.html
+type serviceAccountToken struct {
+	Token               string `json:"token"`
+	ExpirationTimestamp string `json:"expirationTimestamp"`
 }
 
 func NewValidator() *validator.Validate {
@@ -58,12 +105,17 @@ func NewValidator() *validator.Validate {
 
 func NewMountConfig(validator validator.Validate) *MountConfig {
 	return &MountConfig{
-		Path:      "/",
-		validator: validator,
+		Path:             "/",
+		IncludeImports:   true, // imported secrets are shown with the secrets of the folder in the Infisical UI
+		ExpandReferences: ExpandReferencesTrue,
+		StrictReferences: true, // references to unknown secrets fail the mount rather than being mounted as empty strings
+		AuthMethod:       AuthMethodUniversalAuth,
+		TagMatch:         TagMatchAny,
+		validator:        validator,
 	}
 }
 
-func (a *MountConfig) Objects() ([]object, error) {
+func (a *MountConfig) Objects() ([]Object, error) {
 	if a.parsedObjects != nil {
 		return a.parsedObjects, nil
 	}
@@ -72,7 +124,7 @@ func (a *MountConfig) Objects() ([]object, error) {
 		return nil, nil
 	}
 
-	var objects []object
+	var objects []Object
 	objectDecoder := yaml.NewDecoder(strings.NewReader(*a.RawObjects))
 	objectDecoder.KnownFields(true)
 	// Decode returns io.EOF error when empty string is passed
@@ -80,11 +132,117 @@ func (a *MountConfig) Objects() ([]object, error) {
 	if err := objectDecoder.Decode(&objects); err != nil && !errors.Is(err, io.EOF) {
 		return nil, err
 	}
+	for i := range objects {
+		if err := objects[i].compile(); err != nil {
+			return nil, fmt.Errorf("[%d]: %w", i, err)
+		}
+	}
 
 	a.parsedObjects = objects
 	return objects, nil
 }
 
+func (a *MountConfig) Templates() ([]FileTemplate, error) {
+	if a.parsedTemplates != nil {
+		return a.parsedTemplates, nil
+	}
+
+	if a.RawTemplates == nil {
+		return nil, nil
+	}
+
+	var templates []FileTemplate
+	templateDecoder := yaml.NewDecoder(strings.NewReader(*a.RawTemplates))
+	templateDecoder.KnownFields(true)
+	if err := templateDecoder.Decode(&templates); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	for i := range templates {
+		if err := templates[i].compile(); err != nil {
+			return nil, fmt.Errorf("[%d].%w", i, err)
+		}
+	}
+
+	a.parsedTemplates = templates
+	return templates, nil
+}
+
+// Tags returns the slugs of the tags given as a comma separated list.
+func (a *MountConfig) Tags() []string {
+	var tags []string
+	for _, tag := range strings.Split(a.TagSlugs, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// SecretsSource is the folder in Infisical which secrets are listed from, and how references in them are expanded.
+type SecretsSource struct {
+	Project          string
+	Env              string
+	Path             string
+	ExpandReferences string
+}
+
+// DefaultSource returns the folder given by projectSlug, envSlug and secretsPath of the mount.
+func (a *MountConfig) DefaultSource() SecretsSource {
+	return SecretsSource{
+		Project:          a.Project,
+		Env:              a.Env,
+		Path:             a.Path,
+		ExpandReferences: a.ExpandReferences,
+	}
+}
+
+// Source returns the folder the object selects secrets from.
+// Each of projectSlug, envSlug, secretsPath and expandReferences of the object falls back to the one of the mount.
+func (a *MountConfig) Source(object Object) SecretsSource {
+	source := a.DefaultSource()
+	if object.Project != "" {
+		source.Project = object.Project
+	}
+	if object.Env != "" {
+		source.Env = object.Env
+	}
+	if object.Path != "" {
+		source.Path = object.Path
+	}
+	if object.ExpandReferences != "" {
+		source.ExpandReferences = object.ExpandReferences
+	}
+	return source
+}
+
+// ServiceAccountToken returns the token of the mounting pod's service account which is passed by the CSI driver.
+// When ServiceAccountTokenAudience is empty, the only token passed is returned.
+func (a *MountConfig) ServiceAccountToken() (string, error) {
+	if a.CSIPodServiceAccountTokens == "" {
+		return "", errors.New("no service account token is passed by the CSI driver, tokenRequests of the CSIDriver must be configured")
+	}
+
+	var tokens map[string]serviceAccountToken
+	if err := json.Unmarshal([]byte(a.CSIPodServiceAccountTokens), &tokens); err != nil {
+		return "", fmt.Errorf("failed to unmarshal service account tokens: %w", err)
+	}
+
+	if a.ServiceAccountTokenAudience == "" {
+		if len(tokens) != 1 {
+			return "", fmt.Errorf("%d service account tokens are passed, serviceAccountTokenAudience must be specified", len(tokens))
+		}
+		for _, token := range tokens {
+			return token.Token, nil
+		}
+	}
+
+	token, ok := tokens[a.ServiceAccountTokenAudience]
+	if !ok {
+		return "", fmt.Errorf("service account token for audience %q not found", a.ServiceAccountTokenAudience)
+	}
+	return token.Token, nil
+}
+
 func (a *MountConfig) Validate() error {
 	if err := a.validator.Struct(a); err != nil {
 		return err
@@ -94,10 +252,130 @@ func (a *MountConfig) Validate() error {
 	if err != nil {
 		return NewConfigError("objects", err)
 	}
+	var filePaths []string
 	for i, object := range objects {
 		if err := a.validator.Struct(object); err != nil {
 			return NewConfigError("objects", fmt.Errorf("[%d]: %w", i, err))
 		}
+		if object.IsPattern() && !object.IsAggregate() {
+			if object.Alias != "" {
+				return NewConfigError("objects", fmt.Errorf("[%d]: objectAlias must not be set with objectNamePattern or objectNameGlob unless objectFormat is set", i))
+			}
+			if err := a.validateAliasTemplate(object); err != nil {
+				return NewConfigError("objects", fmt.Errorf("[%d]: %w", i, err))
+			}
+			continue
+		}
+		filePath, err := a.ObjectFilePath(object, object.Name)
+		if err != nil {
+			return NewConfigError("objects", fmt.Errorf("[%d]: %w", i, err))
+		}
+		filePaths = append(filePaths, filePath)
+	}
+	if err := ValidateFilePaths(filePaths); err != nil {
+		return NewConfigError("objects", err)
+	}
+	if a.EscapeReferences {
+		// Infisical expands $${ as $ followed by a reference
+		if a.ExpandReferences == ExpandReferencesServer || slices.ContainsFunc(objects, func(object Object) bool { return object.ExpandReferences == ExpandReferencesServer }) {
+			return NewConfigError("escapeReferences", errors.New("references expanded by Infisical cannot be escaped, expandReferences must not be server"))
+		}
+	}
+
+	templates, err := a.Templates()
+	if err != nil {
+		return NewConfigError("templates", err)
+	}
+	for i, template := range templates {
+		if err := a.validator.Struct(template); err != nil {
+			return NewConfigError("templates", fmt.Errorf("[%d]: %w", i, err))
+		}
+		if err := a.validateFilePath(template.Path); err != nil {
+			return NewConfigError("templates", fmt.Errorf("[%d]: %w", i, err))
+		}
+		filePaths = append(filePaths, template.Path)
+	}
+	if err := ValidateFilePaths(filePaths); err != nil {
+		return NewConfigError("templates", err)
+	}
+
+	return nil
+}
+
+// ObjectFilePath returns the path of the file the secret selected by the object is written to.
+// name is the path of the secret relative to the secrets path.
+func (a *MountConfig) ObjectFilePath(object Object, name string) (string, error) {
+	filePath, err := object.filePath(name)
+	if err != nil {
+		return "", err
+	}
+	if object.aliasTemplate != nil {
+		// objectAliasTemplate may render paths into subdirectories, e.g. db/{{ .Name | lower }}
+		err = validateRelativePath(filePath)
+	} else {
+		err = a.validateFilePath(filePath)
+	}
+	if err != nil {
+		return "", err
+	}
+	return filePath, nil
+}
+
+// validateFilePath validates the path of the file an object is written to.
+// Only recursive mounts may write files into subdirectories.
+func (a *MountConfig) validateFilePath(filePath string) error {
+	if !a.Recursive && strings.Contains(filePath, "/") {
+		return fmt.Errorf("file path %s must not contain / unless recursive is true", filePath)
+	}
+	return validateRelativePath(filePath)
+}
+
+// validateRelativePath validates that the file path stays within the mount.
+func validateRelativePath(filePath string) error {
+	if filePath == "" || path.IsAbs(filePath) || path.Clean(filePath) != filePath || filePath == "." || filePath == ".." || strings.HasPrefix(filePath, "../") {
+		return fmt.Errorf("file path %s must be a clean relative path", filePath)
+	}
+	return nil
+}
+
+// validateAliasTemplate validates objectAliasTemplate by rendering it for sample names,
+// since the names of the secrets matching the pattern are unknown until mounted.
+func (a *MountConfig) validateAliasTemplate(object Object) error {
+	if object.aliasTemplate == nil {
+		return nil
+	}
+
+	filePaths := map[string]bool{}
+	for _, name := range aliasTemplateSampleNames {
+		filePath, err := a.ObjectFilePath(object, name)
+		if err != nil {
+			return err
+		}
+		if filePaths[filePath] {
+			return fmt.Errorf("objectAliasTemplate renders %s for different secrets, which makes file paths duplicated", filePath)
+		}
+		filePaths[filePath] = true
+	}
+	return nil
+}
+
+// ValidateFilePaths returns an error when files would collide with each other,
+// either by having the same path or by a file being a parent directory of another.
+func ValidateFilePaths(filePaths []string) error {
+	files := map[string]bool{}
+	for _, filePath := range filePaths {
+		if files[filePath] {
+			return fmt.Errorf("file path %s is duplicated", filePath)
+		}
+		files[filePath] = true
+	}
+
+	for _, filePath := range filePaths {
+		for dir := path.Dir(filePath); dir != "." && dir != "/"; dir = path.Dir(dir) {
+			if files[dir] {
+				return fmt.Errorf("file path %s collides with directory of %s", dir, filePath)
+			}
+		}
 	}
 
 	return nil
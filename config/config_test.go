@@ -1,6 +1,7 @@
 package config_test
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 
@@ -49,12 +50,758 @@ func TestMountConfigValidates(t *testing.T) {
 				}
 			},
 		},
+		{
+			"FailedWithNonHttpsSiteUrl",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.SiteUrl = "http://infisical.example.com"
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+			},
+		},
+		{
+			"FailedWithRelativeSiteUrl",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.SiteUrl = "infisical.example.com"
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+			},
+		},
+		{
+			"SuccessfullyWithKubernetesAuthWithoutAuthSecret",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.AuthMethod = config.AuthMethodKubernetes
+				mountConfig.IdentityID = "test-identity"
+				mountConfig.AuthSecretName = ""
+				mountConfig.AuthSecretNamespace = ""
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithKubernetesAuthWithoutIdentityId",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.AuthMethod = config.AuthMethodKubernetes
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+			},
+		},
+		{
+			"FailedWithAccessTokenWithoutAuthSecret",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.AuthMethod = config.AuthMethodAccessToken
+				mountConfig.AuthSecretName = ""
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+			},
+		},
+		{
+			"SuccessfullyWithAzureAuth",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.AuthMethod = config.AuthMethodAzure
+				mountConfig.IdentityID = "test-identity"
+				mountConfig.AzureResource = "https://example.com/"
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithAzureResourceForOtherAuthMethod",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.AzureResource = "https://example.com/"
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+			},
+		},
+		{
+			"FailedWithUnknownAuthMethod",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.AuthMethod = "unknown"
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+			},
+		},
+		{
+			"FailedWithUnknownTagMatch",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.TagSlugs = "payments-api"
+				mountConfig.TagMatch = "both"
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+			},
+		},
+		{
+			"SuccessfullyWithExpandReferencesByServer",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.ExpandReferences = config.ExpandReferencesServer
+				mountConfig.RawObjects = ptr.String("- objectName: DB_URL\n  expandReferences: false")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithUnknownExpandReferences",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.ExpandReferences = "client"
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+			},
+		},
+		{
+			"FailedWithUnknownExpandReferencesOfObject",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.RawObjects = ptr.String("- objectName: DB_URL\n  expandReferences: client")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if !strings.HasPrefix(err.Error(), "objects: [0]: ") {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithEscapedReferences",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.EscapeReferences = true
+				mountConfig.RawObjects = ptr.String("- objectName: DB_URL\n  expandReferences: false")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithEscapedReferencesExpandedByServer",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.EscapeReferences = true
+				mountConfig.RawObjects = ptr.String("- objectName: DB_URL\n  expandReferences: server")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if !strings.HasPrefix(err.Error(), "escapeReferences: ") {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithNegativeMaxReferenceDepth",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.MaxReferenceDepth = -1
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+			},
+		},
 		{
 			"FailedWithInvalidRawObjects",
 			func(t *testing.T) {
 				// Given
 				mountConfig := idealMountConfig
-				mountConfig.RawObjects = ptr.String("objectName: test")
+				mountConfig.RawObjects = ptr.String("objectName: test")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if !strings.HasPrefix(err.Error(), "objects: ") {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithoutRequiredRawObjectFields",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.RawObjects = ptr.String("- objectAlias: secret")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if !strings.HasPrefix(err.Error(), "objects: [0]: ") {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithInvalidCaractersWithinRawObjectFields",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.RawObjects = ptr.String("- objectName: test\n  objectAlias: path/to/secret")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if !strings.HasPrefix(err.Error(), "objects: [0]: ") {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithSlashWithinRawObjectFieldsWhenRecursive",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.Recursive = true
+				mountConfig.RawObjects = ptr.String("- objectName: db/PASSWORD\n  objectAlias: database/password")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithParentDirectoryWithinRawObjectFieldsWhenRecursive",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.Recursive = true
+				mountConfig.RawObjects = ptr.String("- objectName: db/PASSWORD\n  objectAlias: ../password")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if !strings.HasPrefix(err.Error(), "objects: [0]: ") {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithSameNamesFromOtherFoldersWithAliases",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.RawObjects = ptr.String("- objectName: DB_PASSWORD\n- objectName: DB_PASSWORD\n  objectAlias: PROD_DB_PASSWORD\n  envSlug: prod")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithSameNamesFromOtherFoldersWithoutAliases",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.RawObjects = ptr.String("- objectName: DB_PASSWORD\n- objectName: DB_PASSWORD\n  projectSlug: shared\n  secretsPath: /db")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if !strings.HasPrefix(err.Error(), "objects: ") {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithObjectNamePatternAndAliasTemplate",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.RawObjects = ptr.String("- objectNamePattern: ^DB_.*\n  objectAliasTemplate: '{{ .Name | trimPrefix \"DB_\" | lower }}'")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithObjectNameGlobWhenRecursive",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.Recursive = true
+				mountConfig.RawObjects = ptr.String("- objectNameGlob: db/*\n  objectAliasTemplate: \"database/{{ .Key | lower }}\"")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithInvalidObjectNamePattern",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.RawObjects = ptr.String("- objectNamePattern: \"^DB_(\"")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if !strings.HasPrefix(err.Error(), "objects: [0]: ") {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithObjectNameAndObjectNamePattern",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.RawObjects = ptr.String("- objectName: DB_PASSWORD\n  objectNamePattern: \"^DB_.*\"")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if !strings.HasPrefix(err.Error(), "objects: [0]: ") {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithObjectAliasTemplateWithoutPattern",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.RawObjects = ptr.String("- objectName: DB_PASSWORD\n  objectAliasTemplate: \"{{ .Name | lower }}\"")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if !strings.HasPrefix(err.Error(), "objects: [0]: ") {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithObjectAliasTemplateEscapingSecretsDirectory",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.Recursive = true
+				mountConfig.RawObjects = ptr.String("- objectNamePattern: \"^DB_.*\"\n  objectAliasTemplate: \"../{{ .Name }}\"")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if !strings.HasPrefix(err.Error(), "objects: [0]: ") {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithObjectAliasTemplateIntoSubdirectoryWhenNotRecursive",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.RawObjects = ptr.String("- objectNamePattern: \"^DB_.*\"\n  objectAliasTemplate: \"db/{{ .Name | lower }}\"")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithObjectAliasTemplateDuplicatingFilePaths",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.RawObjects = ptr.String("- objectNamePattern: \"^DB_.*\"\n  objectAliasTemplate: database")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if !strings.HasPrefix(err.Error(), "objects: [0]: ") {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithOptionalObjectsAndDefaultValues",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.RawObjects = ptr.String("- objectName: FEATURE_FLAG\n  optional: true\n- objectName: LOG_LEVEL\n  default: \"\"")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithOptionalObjectNamePattern",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.RawObjects = ptr.String("- objectNamePattern: ^DB_\n  optional: true")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if !strings.HasPrefix(err.Error(), "objects: [0]: ") {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithAggregateObjects",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.RawObjects = ptr.String("- objectFormat: dotenv\n  objectNames: [DB_USERNAME, DB_PASSWORD]\n  objectAlias: .env\n  mode: \"0400\"\n- objectFormat: json\n  objectNamePattern: ^DB_\n  objectAlias: db.json")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithUnknownObjectFormat",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.RawObjects = ptr.String("- objectFormat: ini\n  objectAlias: config.ini")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if !strings.HasPrefix(err.Error(), "objects: [0]: ") {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithAggregateObjectWithoutObjectAlias",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.RawObjects = ptr.String("- objectFormat: dotenv")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if !strings.HasPrefix(err.Error(), "objects: [0]: ") {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithObjectNamesWithoutObjectFormat",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.RawObjects = ptr.String("- objectNames: [DB_USERNAME]")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if !strings.HasPrefix(err.Error(), "objects: [0]: ") {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithNonOctalMode",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.RawObjects = ptr.String("- objectFormat: dotenv\n  objectAlias: .env\n  mode: \"0800\"")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if !strings.HasPrefix(err.Error(), "objects: [0]: ") {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithTemplates",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.RawTemplates = ptr.String("- path: pgpass\n  mode: \"0600\"\n  template: '{{ .Secrets.DB_HOST }}:5432:*:{{ .Secrets.DB_USER | quote }}'")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithUnparsableTemplate",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.RawTemplates = ptr.String("- path: pgpass\n  template: '{{ .Secrets.DB_HOST | unknown }}'")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if !strings.HasPrefix(err.Error(), "templates: [0].template: ") {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithTemplateWithoutPath",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.RawTemplates = ptr.String("- template: '{{ .Secrets.DB_HOST }}'")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if !strings.HasPrefix(err.Error(), "templates: [0]") {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithTemplateCollidingWithObject",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.RawTemplates = ptr.String("- path: test\n  template: '{{ .Secrets.DB_HOST }}'")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if !strings.HasPrefix(err.Error(), "templates: file path test is duplicated") {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithEncodings",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.RawObjects = ptr.String("- objectName: KEYSTORE\n  encoding: base64\n- objectName: KEYTAB\n  encoding: hex\n- objectName: MESSAGE\n  encoding: utf-8")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithUnknownEncoding",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.RawObjects = ptr.String("- objectName: KEYSTORE\n  encoding: base32")
 
 				// When
 				err := mountConfig.Validate()
@@ -63,17 +810,17 @@ func TestMountConfigValidates(t *testing.T) {
 				if err == nil {
 					t.Errorf("expected error, got nil")
 				}
-				if !strings.HasPrefix(err.Error(), "objects: ") {
+				if !strings.HasPrefix(err.Error(), "objects: [0]: ") {
 					t.Errorf("unexpected error: %s", err)
 				}
 			},
 		},
 		{
-			"FailedWithoutRequiredRawObjectFields",
+			"FailedWithEncodingOfAggregateObject",
 			func(t *testing.T) {
 				// Given
 				mountConfig := idealMountConfig
-				mountConfig.RawObjects = ptr.String("- objectAlias: secret")
+				mountConfig.RawObjects = ptr.String("- objectFormat: dotenv\n  objectAlias: .env\n  encoding: base64")
 
 				// When
 				err := mountConfig.Validate()
@@ -88,11 +835,81 @@ func TestMountConfigValidates(t *testing.T) {
 			},
 		},
 		{
-			"FailedWithInvalidCaractersWithinRawObjectFields",
+			"SuccessfullyWithFileModes",
 			func(t *testing.T) {
 				// Given
 				mountConfig := idealMountConfig
-				mountConfig.RawObjects = ptr.String("- objectName: test\n  objectAlias: path/to/secret")
+				mountConfig.RawObjects = ptr.String("- objectName: SSH_KEY\n  mode: 0400\n- objectNameGlob: CA_*\n  mode: \"0o444\"")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithNonOctalModeOfObject",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.RawObjects = ptr.String("- objectName: SSH_KEY\n  mode: rw-------")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if !strings.HasPrefix(err.Error(), "objects: [0]: mode: ") {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithVersions",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.RawObjects = ptr.String("- objectName: DB_PASSWORD\n  version: 12\n- objectName: DB_USERNAME\n  version: latest")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithInvalidVersion",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.RawObjects = ptr.String("- objectName: DB_PASSWORD\n  version: v12")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if !strings.HasPrefix(err.Error(), "objects: [0]: ") {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithVersionOfObjectNamePattern",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.RawObjects = ptr.String("- objectNamePattern: ^DB_\n  version: 12")
 
 				// When
 				err := mountConfig.Validate()
@@ -106,6 +923,45 @@ func TestMountConfigValidates(t *testing.T) {
 				}
 			},
 		},
+		{
+			"FailedWithDuplicatedFilePaths",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.RawObjects = ptr.String("- objectName: test\n- objectName: other\n  objectAlias: test")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if !strings.HasPrefix(err.Error(), "objects: ") {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithFilePathCollidingWithDirectoryWhenRecursive",
+			func(t *testing.T) {
+				// Given
+				mountConfig := idealMountConfig
+				mountConfig.Recursive = true
+				mountConfig.RawObjects = ptr.String("- objectName: db\n- objectName: db/PASSWORD")
+
+				// When
+				err := mountConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if !strings.HasPrefix(err.Error(), "objects: ") {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
 	} {
 		validate = config.NewValidator()
 		idealMountConfig = config.NewMountConfig(*validate)
@@ -114,6 +970,7 @@ func TestMountConfigValidates(t *testing.T) {
 		idealMountConfig.Path = "/path/to/secrets"
 		idealMountConfig.AuthSecretName = "test"
 		idealMountConfig.AuthSecretNamespace = "test-namepace"
+		idealMountConfig.SiteUrl = "https://infisical.example.com"
 		idealMountConfig.RawObjects = ptr.String("- objectName: test")
 
 		t.Run(testcase.name, testcase.f)
@@ -211,3 +1068,207 @@ func TestMountConfigGetObjects(t *testing.T) {
 		t.Run(testcase.name, testcase.f)
 	}
 }
+
+func TestMountConfigGetServiceAccountToken(t *testing.T) {
+	var (
+		validate *validator.Validate
+	)
+
+	for _, testcase := range []struct {
+		name string
+		f    func(t *testing.T)
+	}{
+		{
+			"SuccessfullyWithSingleToken",
+			func(t *testing.T) {
+				// Given
+				mountConfig := config.NewMountConfig(*validate)
+				mountConfig.CSIPodServiceAccountTokens = `{"infisical":{"token":"test-token","expirationTimestamp":"2024-01-01T00:00:00Z"}}`
+
+				// When
+				token, err := mountConfig.ServiceAccountToken()
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if token != "test-token" {
+					t.Errorf("unexpected token: %s", token)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithAudience",
+			func(t *testing.T) {
+				// Given
+				mountConfig := config.NewMountConfig(*validate)
+				mountConfig.ServiceAccountTokenAudience = "infisical"
+				mountConfig.CSIPodServiceAccountTokens = `{"infisical":{"token":"test-token"},"other":{"token":"other-token"}}`
+
+				// When
+				token, err := mountConfig.ServiceAccountToken()
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if token != "test-token" {
+					t.Errorf("unexpected token: %s", token)
+				}
+			},
+		},
+		{
+			"FailedWithoutTokens",
+			func(t *testing.T) {
+				// Given
+				mountConfig := config.NewMountConfig(*validate)
+
+				// When
+				_, err := mountConfig.ServiceAccountToken()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+			},
+		},
+		{
+			"FailedWithMultipleTokensWithoutAudience",
+			func(t *testing.T) {
+				// Given
+				mountConfig := config.NewMountConfig(*validate)
+				mountConfig.CSIPodServiceAccountTokens = `{"infisical":{"token":"test-token"},"other":{"token":"other-token"}}`
+
+				// When
+				_, err := mountConfig.ServiceAccountToken()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+			},
+		},
+		{
+			"FailedWithUnknownAudience",
+			func(t *testing.T) {
+				// Given
+				mountConfig := config.NewMountConfig(*validate)
+				mountConfig.ServiceAccountTokenAudience = "unknown"
+				mountConfig.CSIPodServiceAccountTokens = `{"infisical":{"token":"test-token"}}`
+
+				// When
+				_, err := mountConfig.ServiceAccountToken()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+			},
+		},
+	} {
+		validate = config.NewValidator()
+
+		t.Run(testcase.name, testcase.f)
+	}
+}
+
+func TestMountConfigGetTags(t *testing.T) {
+	var (
+		validate *validator.Validate
+	)
+
+	for _, testcase := range []struct {
+		name string
+		f    func(t *testing.T)
+	}{
+		{
+			"SuccessfullyWithCommaSeparatedTags",
+			func(t *testing.T) {
+				// Given
+				mountConfig := config.NewMountConfig(*validate)
+				mountConfig.TagSlugs = "payments-api, orders-api,,"
+
+				// When
+				tags := mountConfig.Tags()
+
+				// Then
+				if strings.Join(tags, "|") != "payments-api|orders-api" {
+					t.Errorf("unexpected tags: %v", tags)
+				}
+				if mountConfig.TagMatch != config.TagMatchAny {
+					t.Errorf("unexpected tagMatch: %s", mountConfig.TagMatch)
+				}
+			},
+		},
+		{
+			"ReturnsEmptyWithoutTags",
+			func(t *testing.T) {
+				// Given
+				mountConfig := config.NewMountConfig(*validate)
+
+				// When
+				tags := mountConfig.Tags()
+
+				// Then
+				if len(tags) != 0 {
+					t.Errorf("unexpected tags: %v", tags)
+				}
+			},
+		},
+	} {
+		validate = config.NewValidator()
+
+		t.Run(testcase.name, testcase.f)
+	}
+}
+
+func TestMountConfigGetSourcesOfObjects(t *testing.T) {
+	var (
+		validate *validator.Validate
+	)
+
+	for _, testcase := range []struct {
+		name string
+		f    func(t *testing.T)
+	}{
+		{
+			"SuccessfullyWithOverrides",
+			func(t *testing.T) {
+				// Given
+				mountConfig := config.NewMountConfig(*validate)
+				mountConfig.Project = "test-project"
+				mountConfig.Env = "dev"
+				objects := "- objectName: DB_USER\n- objectName: DB_PASSWORD\n  envSlug: prod\n- objectName: API_KEY\n  projectSlug: shared\n  secretsPath: /api\n- objectName: TEMPLATE\n  expandReferences: false"
+				mountConfig.RawObjects = &objects
+				parsed, err := mountConfig.Objects()
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+
+				// When
+				var sources []config.SecretsSource
+				for _, object := range parsed {
+					sources = append(sources, mountConfig.Source(object))
+				}
+
+				// Then
+				expected := []config.SecretsSource{
+					{Project: "test-project", Env: "dev", Path: "/", ExpandReferences: config.ExpandReferencesTrue},
+					{Project: "test-project", Env: "prod", Path: "/", ExpandReferences: config.ExpandReferencesTrue},
+					{Project: "shared", Env: "dev", Path: "/api", ExpandReferences: config.ExpandReferencesTrue},
+					{Project: "test-project", Env: "dev", Path: "/", ExpandReferences: config.ExpandReferencesFalse},
+				}
+				if !reflect.DeepEqual(sources, expected) {
+					t.Errorf("unexpected sources: %v", sources)
+				}
+				if sources[0] != mountConfig.DefaultSource() {
+					t.Errorf("unexpected default source: %v", mountConfig.DefaultSource())
+				}
+			},
+		},
+	} {
+		validate = config.NewValidator()
+
+		t.Run(testcase.name, testcase.f)
+	}
+}
@@ -0,0 +1,28 @@
+package config
+
+import "log/slog"
+
+// Redacted replaces values which must not be written to logs.
+const Redacted = "[REDACTED]"
+
+var _ slog.LogValuer = &MountConfig{}
+
+// LogValue implements slog.LogValuer.
+// Only the attributes identifying the mount and the secrets are kept, and service account tokens are masked.
+func (c *MountConfig) LogValue() slog.Value {
+	attrs := []slog.Attr{
+		slog.String("pod.name", c.CSIPodName),
+		slog.String("pod.namespace", c.CSIPodNamespace),
+		slog.String("secretProviderClass", c.SecretProviderClass),
+		slog.String("projectSlug", c.Project),
+		slog.String("envSlug", c.Env),
+		slog.String("secretsPath", c.Path),
+		slog.String("siteUrl", c.SiteUrl),
+		slog.String("authMethod", c.AuthMethod),
+	}
+	if c.CSIPodServiceAccountTokens != "" {
+		attrs = append(attrs, slog.String("serviceAccount.tokens", Redacted))
+	}
+
+	return slog.GroupValue(attrs...)
+}
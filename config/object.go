@@ -0,0 +1,223 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const (
+	ObjectFormatDotenv     = "dotenv"
+	ObjectFormatJSON       = "json"
+	ObjectFormatYAML       = "yaml"
+	ObjectFormatProperties = "properties"
+	ObjectFormatTOML       = "toml"
+)
+
+// ObjectVersionLatest selects the latest version of a secret, which is the default.
+const ObjectVersionLatest = "latest"
+
+const (
+	ObjectEncodingBase64 = "base64"
+	ObjectEncodingHex    = "hex"
+	ObjectEncodingUTF8   = "utf-8"
+)
+
+// Object selects the secrets to be mounted.
+// A secret is selected by its name with objectName, or secrets are selected by a regular expression with objectNamePattern
+// or by a glob with objectNameGlob.
+// A secret selected by its name must exist, unless the object is optional to skip it or has a default value to be written instead.
+// With objectFormat, the selected secrets are rendered into the single file objectAlias instead,
+// where the secrets are selected by names with objectNames, by a pattern, or all secrets without them.
+// Values are written as they are, or decoded from the encoding, which allows binary files to be stored in Infisical.
+// Files are written with mode, or the permission of the volume without it.
+// A secret selected by its name may be pinned to a version instead of the latest one.
+// Secrets are selected from projectSlug, envSlug and secretsPath of the object, each of which falls back to the one of the mount,
+// and so does expandReferences.
+type Object struct {
+	Name             string   `yaml:"objectName" validate:"required_without_all=NamePattern NameGlob Format,excluded_with=NamePattern NameGlob Format"`
+	NamePattern      string   `yaml:"objectNamePattern" validate:"excluded_with=NameGlob"`
+	NameGlob         string   `yaml:"objectNameGlob"`
+	Names            []string `yaml:"objectNames" validate:"excluded_without=Format,excluded_with=NamePattern NameGlob,dive,required"`
+	Format           string   `yaml:"objectFormat" validate:"omitempty,oneof=dotenv json yaml properties toml"`
+	Alias            string   `yaml:"objectAlias" validate:"required_with=Format"`
+	AliasTemplate    string   `yaml:"objectAliasTemplate" validate:"excluded_without_all=NamePattern NameGlob,excluded_with=Format"`
+	Mode             string   `yaml:"mode"`
+	Encoding         string   `yaml:"encoding" validate:"omitempty,oneof=base64 hex utf-8,excluded_with=Format"`
+	Version          string   `yaml:"version" validate:"omitempty,excluded_with=NamePattern NameGlob Format"`
+	Optional         bool     `yaml:"optional" validate:"excluded_with=NamePattern NameGlob Format"`
+	Default          *string  `yaml:"default" validate:"excluded_with=NamePattern NameGlob Format Optional"`
+	Project          string   `yaml:"projectSlug"`
+	Env              string   `yaml:"envSlug"`
+	Path             string   `yaml:"secretsPath"`
+	ExpandReferences string   `yaml:"expandReferences" validate:"omitempty,oneof=true false server"`
+	namePattern      *regexp.Regexp
+	aliasTemplate    *template.Template
+	mode             *int32
+}
+
+// aliasTemplateData is passed to objectAliasTemplate.
+type aliasTemplateData struct {
+	// Name is the path of the secret relative to the secrets path, which is the key of the secret unless the mount is recursive.
+	Name string
+	// Key is the key of the secret.
+	Key string
+}
+
+// aliasTemplateFuncs are the functions available in objectAliasTemplate.
+// The value of a pipeline is passed as the last argument.
+var aliasTemplateFuncs = template.FuncMap{
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"replace":    func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+	"trimPrefix": func(prefix, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix, s string) string { return strings.TrimSuffix(s, suffix) },
+}
+
+// aliasTemplateSampleNames are the names objectAliasTemplate is validated with.
+var aliasTemplateSampleNames = []string{"SAMPLE_SECRET_1", "SAMPLE_SECRET_2"}
+
+// compile compiles objectNamePattern, objectNameGlob and objectAliasTemplate.
+func (o *Object) compile() error {
+	if o.NamePattern != "" {
+		pattern, err := regexp.Compile(o.NamePattern)
+		if err != nil {
+			return fmt.Errorf("objectNamePattern: %w", err)
+		}
+		o.namePattern = pattern
+	}
+	if o.NameGlob != "" {
+		if _, err := path.Match(o.NameGlob, ""); err != nil {
+			return fmt.Errorf("objectNameGlob: %w", err)
+		}
+	}
+	if o.Version != "" && o.Version != ObjectVersionLatest {
+		if version, err := strconv.Atoi(o.Version); err != nil || version <= 0 {
+			return fmt.Errorf("version: %s must be a positive integer or %s", o.Version, ObjectVersionLatest)
+		}
+	}
+	if o.Mode != "" {
+		mode, err := parseFileMode(o.Mode)
+		if err != nil {
+			return err
+		}
+		o.mode = &mode
+	}
+	if o.AliasTemplate != "" {
+		aliasTemplate, err := template.New("objectAliasTemplate").Funcs(aliasTemplateFuncs).Option("missingkey=error").Parse(o.AliasTemplate)
+		if err != nil {
+			return fmt.Errorf("objectAliasTemplate: %w", err)
+		}
+		o.aliasTemplate = aliasTemplate
+	}
+	return nil
+}
+
+// IsPattern reports whether the object selects secrets by a pattern instead of the name.
+func (o Object) IsPattern() bool {
+	return o.NamePattern != "" || o.NameGlob != ""
+}
+
+// IsAggregate reports whether the object renders the selected secrets into a single file.
+func (o Object) IsAggregate() bool {
+	return o.Format != ""
+}
+
+// Required reports whether the mount fails when the secret selected by the name is not found.
+func (o Object) Required() bool {
+	return o.Name != "" && !o.Optional && o.Default == nil
+}
+
+// Matches reports whether the object selects the secret.
+// name is the path of the secret relative to the secrets path.
+func (o Object) Matches(name string) bool {
+	switch {
+	case o.namePattern != nil:
+		return o.namePattern.MatchString(name)
+	case o.NameGlob != "":
+		matched, _ := path.Match(o.NameGlob, name)
+		return matched
+	case o.IsAggregate():
+		return len(o.Names) == 0 || slices.Contains(o.Names, name)
+	default:
+		return o.Name == name
+	}
+}
+
+// WritesTo reports whether the object writes a file to the path, which is the objectName of secretObjects of SecretProviderClass.
+// known is false when it cannot be told until mounted, since the paths rendered by objectAliasTemplate depend on the names of the secrets.
+func (o Object) WritesTo(filePath string) (writes, known bool) {
+	switch {
+	case o.IsAggregate():
+		return o.Alias == filePath, true
+	case o.aliasTemplate != nil:
+		return false, false
+	case o.IsPattern():
+		return o.Matches(filePath), true
+	case o.Alias != "":
+		return o.Alias == filePath, true
+	default:
+		return o.Name == filePath, true
+	}
+}
+
+// PinnedVersion returns the version of the secret the object is pinned to, or 0 for the latest version.
+func (o Object) PinnedVersion() int {
+	version, _ := strconv.Atoi(o.Version)
+	return version
+}
+
+// Decode decodes the value of the secret with the encoding of the object.
+// Whitespaces such as line breaks are ignored in base64 and hex.
+func (o Object) Decode(value string) ([]byte, error) {
+	switch o.Encoding {
+	case ObjectEncodingBase64:
+		return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(value), ""))
+	case ObjectEncodingHex:
+		return hex.DecodeString(strings.Join(strings.Fields(value), ""))
+	default:
+		return []byte(value), nil
+	}
+}
+
+// parseFileMode parses the permission of a file given as an octal string, optionally prefixed with 0o.
+func parseFileMode(s string) (int32, error) {
+	mode, err := strconv.ParseUint(strings.TrimPrefix(s, "0o"), 8, 32)
+	if err != nil || mode > 0o777 {
+		return 0, fmt.Errorf("mode: %s must be an octal file permission such as 0400", s)
+	}
+	return int32(mode), nil
+}
+
+// FileMode returns the permission of the file the object is written to, or the given default.
+func (o Object) FileMode(defaultMode int32) int32 {
+	if o.mode == nil {
+		return defaultMode
+	}
+	return *o.mode
+}
+
+func (o Object) filePath(name string) (string, error) {
+	switch {
+	case o.aliasTemplate != nil:
+		var filePath strings.Builder
+		if err := o.aliasTemplate.Execute(&filePath, aliasTemplateData{Name: name, Key: path.Base(name)}); err != nil {
+			return "", fmt.Errorf("objectAliasTemplate: %w", err)
+		}
+		if filePath.Len() == 0 {
+			return "", errors.New("objectAliasTemplate renders an empty file path")
+		}
+		return filePath.String(), nil
+	case o.Alias != "":
+		return o.Alias, nil
+	default:
+		return name, nil
+	}
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"text/template"
+)
+
+// FileTemplate renders a file from the secrets with a Go template.
+// The template is given .Secrets, the values of all the listed secrets keyed by their names, and the functions in templateFuncs only.
+type FileTemplate struct {
+	Path     string `yaml:"path" validate:"required"`
+	Template string `yaml:"template" validate:"required"`
+	Mode     string `yaml:"mode"`
+	parsed   *template.Template
+	mode     *int32
+}
+
+// templateData is passed to templates.
+type templateData struct {
+	Secrets map[string]string
+}
+
+// templateFuncs are the functions available in templates.
+// The value of a pipeline is passed as the last argument.
+var templateFuncs = template.FuncMap{
+	"base64": func(s string) string {
+		return base64.StdEncoding.EncodeToString([]byte(s))
+	},
+	"json": func(v any) (string, error) {
+		encoded, err := json.Marshal(v)
+		return string(encoded), err
+	},
+	"quote": strconv.Quote,
+	"default": func(defaultValue, s string) string {
+		if s == "" {
+			return defaultValue
+		}
+		return s
+	},
+	"required": func(message, s string) (string, error) {
+		if s == "" {
+			return "", errors.New(message)
+		}
+		return s, nil
+	},
+}
+
+// compile parses the template and the mode.
+// Missing keys of .Secrets fail the rendering, while index returns an empty string for them.
+func (t *FileTemplate) compile() error {
+	parsed, err := template.New(t.Path).Funcs(templateFuncs).Option("missingkey=error").Parse(t.Template)
+	if err != nil {
+		return NewConfigError("template", err)
+	}
+	t.parsed = parsed
+
+	if t.Mode != "" {
+		mode, err := parseFileMode(t.Mode)
+		if err != nil {
+			return err
+		}
+		t.mode = &mode
+	}
+	return nil
+}
+
+// Render renders the file from the secrets keyed by their names.
+func (t FileTemplate) Render(secrets map[string]string) ([]byte, error) {
+	var contents bytes.Buffer
+	if err := t.parsed.Execute(&contents, templateData{Secrets: secrets}); err != nil {
+		return nil, NewConfigError("template", err)
+	}
+	return contents.Bytes(), nil
+}
+
+// FileMode returns the permission of the file, or the given default.
+func (t FileTemplate) FileMode(defaultMode int32) int32 {
+	if t.mode == nil {
+		return defaultMode
+	}
+	return *t.mode
+}
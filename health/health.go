@@ -0,0 +1,99 @@
+// Package health serves the liveness and the readiness of the provider over HTTP.
+package health
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/infisical/go-sdk/packages/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"sigs.k8s.io/secrets-store-csi-driver/provider/v1alpha1"
+)
+
+const checkTimeout = 5 * time.Second
+
+// Server answers /healthz when the gRPC socket is served,
+// and /readyz when additionally the Infisical site is reachable if checkSiteUrl is enabled.
+type Server struct {
+	socketPath   string
+	siteUrl      string
+	checkSiteUrl bool
+	httpClient   *http.Client
+}
+
+// NewServer returns a health server for the provider serving socketPath.
+// siteUrl is the default Infisical site of the provider, and an empty one is Infisical Cloud.
+func NewServer(socketPath, siteUrl string, checkSiteUrl bool) *Server {
+	if siteUrl == "" {
+		siteUrl = util.DEFAULT_INFISICAL_API_URL
+	}
+
+	return &Server{
+		socketPath:   socketPath,
+		siteUrl:      siteUrl,
+		checkSiteUrl: checkSiteUrl,
+		httpClient:   &http.Client{Timeout: checkTimeout},
+	}
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		respond(w, s.checkSocket(r.Context()))
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		err := s.checkSocket(r.Context())
+		if err == nil && s.checkSiteUrl {
+			err = s.checkSite(r.Context())
+		}
+		respond(w, err)
+	})
+	return mux
+}
+
+// checkSocket calls the Version RPC of the provider over the socket.
+func (s *Server) checkSocket(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	conn, err := grpc.NewClient("unix://"+s.socketPath, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return fmt.Errorf("failed to connect to the socket, error: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := v1alpha1.NewCSIDriverProviderClient(conn).Version(ctx, &v1alpha1.VersionRequest{Version: "v1alpha1"}); err != nil {
+		return fmt.Errorf("failed to call the Version RPC, error: %w", err)
+	}
+	return nil
+}
+
+// checkSite calls the status endpoint of the Infisical site.
+func (s *Server) checkSite(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, util.AppendAPIEndpoint(s.siteUrl)+"/status", nil)
+	if err != nil {
+		return err
+	}
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s, error: %w", s.siteUrl, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status of %s: %s", s.siteUrl, res.Status)
+	}
+	return nil
+}
+
+func respond(w http.ResponseWriter, err error) {
+	if err != nil {
+		slog.Warn("health check failed", "error", err)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	_, _ = w.Write([]byte("ok"))
+}
@@ -0,0 +1,135 @@
+package health_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/health"
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/server"
+)
+
+func TestServerProbes(t *testing.T) {
+	var (
+		socketPath     string
+		providerServer *server.CSIProviderServer
+	)
+
+	for _, testcase := range []struct {
+		name string
+		f    func(t *testing.T)
+	}{
+		{
+			"SuccessfullyWithServedSocket",
+			func(t *testing.T) {
+				// Given
+				if err := providerServer.Start(); err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				defer providerServer.Stop()
+				healthServer := health.NewServer(socketPath, "", false)
+
+				// When
+				recorder := httptest.NewRecorder()
+				healthServer.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+				// Then
+				if recorder.Code != http.StatusOK {
+					t.Errorf("unexpected status: %d", recorder.Code)
+				}
+			},
+		},
+		{
+			"FailedWithoutServedSocket",
+			func(t *testing.T) {
+				// Given
+				healthServer := health.NewServer(socketPath, "", false)
+
+				// When
+				recorder := httptest.NewRecorder()
+				healthServer.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+				// Then
+				if recorder.Code != http.StatusServiceUnavailable {
+					t.Errorf("unexpected status: %d", recorder.Code)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithReachableSiteUrl",
+			func(t *testing.T) {
+				// Given
+				if err := providerServer.Start(); err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				defer providerServer.Stop()
+				site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path != "/api/status" {
+						w.WriteHeader(http.StatusNotFound)
+					}
+				}))
+				defer site.Close()
+				healthServer := health.NewServer(socketPath, site.URL, true)
+
+				// When
+				recorder := httptest.NewRecorder()
+				healthServer.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+				// Then
+				if recorder.Code != http.StatusOK {
+					t.Errorf("unexpected status: %d", recorder.Code)
+				}
+			},
+		},
+		{
+			"FailedWithUnreachableSiteUrl",
+			func(t *testing.T) {
+				// Given
+				if err := providerServer.Start(); err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				defer providerServer.Stop()
+				site := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusBadGateway)
+				}))
+				defer site.Close()
+				healthServer := health.NewServer(socketPath, site.URL, true)
+
+				// When
+				recorder := httptest.NewRecorder()
+				healthServer.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+				// Then
+				if recorder.Code != http.StatusServiceUnavailable {
+					t.Errorf("unexpected status: %d", recorder.Code)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithoutSiteUrlCheck",
+			func(t *testing.T) {
+				// Given
+				if err := providerServer.Start(); err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				defer providerServer.Stop()
+				healthServer := health.NewServer(socketPath, "https://unreachable.invalid", false)
+
+				// When
+				recorder := httptest.NewRecorder()
+				healthServer.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+				// Then
+				if recorder.Code != http.StatusOK {
+					t.Errorf("unexpected status: %d", recorder.Code)
+				}
+			},
+		},
+	} {
+		socketPath = filepath.Join(t.TempDir(), "test.sock")
+		providerServer = server.NewCSIProviderServer("0.0.1", socketPath, "", nil, nil)
+
+		t.Run(testcase.name, testcase.f)
+	}
+}
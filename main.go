@@ -5,13 +5,22 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"log/slog"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/auth"
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/config"
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/health"
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/metrics"
 	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/provider"
 	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/server"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 )
@@ -19,6 +28,19 @@ import (
 var (
 	runtimeVersion = "0.4.3"
 	versionFlag    = flag.Bool("version", false, "print version information")
+	siteUrlFlag    = flag.String("infisical-site-url", "", "default Infisical site URL used when a SecretProviderClass does not specify siteUrl")
+	logLevelFlag   = flag.String("log-level", "info", "log level (debug, info, warn or error)")
+	metricsAddress = flag.String("metrics-listen-address", "", "Prometheus metrics server listen address, metrics are not served if empty")
+	healthAddress  = flag.String("health-listen-address", "", "health server listen address serving /healthz and /readyz, health is not served if empty")
+	readySiteFlag  = flag.Bool("readiness-check-site-url", false, "report not ready while the default Infisical site URL is unreachable")
+	versionKeyFile = flag.String("object-version-key-file", "", "file containing the HMAC key of object versions, values are not hashed if empty")
+	retryAttempts  = flag.Int("infisical-retry-max-attempts", provider.DefaultRetryConfig.MaxAttempts, "max number of attempts of idempotent Infisical API calls, including the first one")
+	retryBackoff   = flag.Duration("infisical-retry-initial-backoff", provider.DefaultRetryConfig.InitialBackoff, "backoff before the first retry of Infisical API calls, doubled for each retry")
+	retryMaxWait   = flag.Duration("infisical-retry-max-backoff", provider.DefaultRetryConfig.MaxBackoff, "max backoff between retries of Infisical API calls")
+	staleGrace     = flag.Duration("last-known-good-grace-period", 0, "period for which the secrets last listed are served while Infisical is unavailable, they are not cached if zero")
+	staleDir       = flag.String("last-known-good-dir", "", "directory storing the secrets last listed encrypted across restarts, they are kept only in memory if empty")
+	staleKeyFile   = flag.String("last-known-good-key-file", "", "file containing the key encrypting the secrets stored in last-known-good-dir")
+	listCacheTTL   = flag.Duration("list-secrets-cache-ttl", 5*time.Second, "period for which secrets listed are shared between mounts with the same identity, only concurrent lists are shared if zero")
 )
 
 func main() {
@@ -27,6 +49,22 @@ func main() {
 		fmt.Println(runtimeVersion)
 		os.Exit(0)
 	}
+	if err := config.NewValidator().Var(*siteUrlFlag, "omitempty,url,startswith=https://"); err != nil {
+		panic(fmt.Errorf("invalid infisical site url: %v", err))
+	}
+	var logLevel slog.Level
+	if err := logLevel.UnmarshalText([]byte(*logLevelFlag)); err != nil {
+		panic(fmt.Errorf("invalid log level: %v", err))
+	}
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: logLevel})))
+	retryConfig := provider.RetryConfig{
+		MaxAttempts:    *retryAttempts,
+		InitialBackoff: *retryBackoff,
+		MaxBackoff:     *retryMaxWait,
+	}
+	if err := retryConfig.Validate(); err != nil {
+		panic(fmt.Errorf("invalid retry config: %v", err))
+	}
 
 	socketPath := "/etc/kubernetes/secrets-store-csi-providers/infisical.sock"
 	_ = os.MkdirAll("/etc/kubernetes/secrets-store-csi-providers", 0755)
@@ -39,8 +77,31 @@ func main() {
 	kubeClient := kubernetes.NewForConfigOrDie(kubeConfig)
 
 	auth := auth.NewAuth(kubeClient)
-	infisicalClientFactory := provider.NewInfisicalClientFactory()
-	provider := server.NewCSIProviderServer(runtimeVersion, socketPath, auth, infisicalClientFactory)
+	infisicalClientFactory := provider.NewInfisicalClientFactory(retryConfig)
+	serverOptions := []server.Option{
+		server.WithListSecretsCoalescer(provider.NewListSecretsCoalescer(*listCacheTTL)),
+	}
+	if *versionKeyFile != "" {
+		key, err := os.ReadFile(*versionKeyFile)
+		if err != nil {
+			panic(fmt.Errorf("unable to read object version key: %v", err))
+		}
+		serverOptions = append(serverOptions, server.WithObjectVersionKey(key))
+	}
+	if *staleGrace > 0 {
+		var key []byte
+		if *staleKeyFile != "" {
+			if key, err = os.ReadFile(*staleKeyFile); err != nil {
+				panic(fmt.Errorf("unable to read last known good key: %v", err))
+			}
+		}
+		cache, err := provider.NewLastKnownGoodCache(*staleGrace, *staleDir, key)
+		if err != nil {
+			panic(fmt.Errorf("unable to create last known good cache: %v", err))
+		}
+		serverOptions = append(serverOptions, server.WithLastKnownGoodCache(cache))
+	}
+	provider := server.NewCSIProviderServer(runtimeVersion, socketPath, *siteUrlFlag, auth, infisicalClientFactory, serverOptions...)
 	defer provider.Stop()
 
 	if err := provider.Start(); err != nil {
@@ -48,6 +109,31 @@ func main() {
 	}
 
 	log.Printf("server started at: %s\n", socketPath)
+
+	if *metricsAddress != "" {
+		registry := prometheus.NewRegistry()
+		registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+		if err := metrics.Register(registry); err != nil {
+			panic(fmt.Errorf("unable to register metrics: %v", err))
+		}
+		go func() {
+			log.Printf("metrics listening on %s\n", *metricsAddress)
+			if err := http.ListenAndServe(*metricsAddress, promhttp.HandlerFor(registry, promhttp.HandlerOpts{})); err != nil {
+				panic(fmt.Errorf("unable to serve metrics: %v", err))
+			}
+		}()
+	}
+
+	if *healthAddress != "" {
+		healthServer := health.NewServer(socketPath, *siteUrlFlag, *readySiteFlag)
+		go func() {
+			log.Printf("health listening on %s\n", *healthAddress)
+			if err := http.ListenAndServe(*healthAddress, healthServer.Handler()); err != nil {
+				panic(fmt.Errorf("unable to serve health: %v", err))
+			}
+		}()
+	}
+
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 	<-ctx.Done()
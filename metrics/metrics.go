@@ -0,0 +1,140 @@
+// Package metrics defines the Prometheus metrics of the provider.
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"sigs.k8s.io/secrets-store-csi-driver/provider/v1alpha1"
+)
+
+const namespace = "infisical_csi_provider"
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+
+	CacheHit     = "hit"
+	CacheRenewed = "renewed"
+	CacheMiss    = "miss"
+	CacheShared  = "shared"
+)
+
+var (
+	// GRPCRequests counts the gRPC requests by method, outcome and error code of the mount response.
+	GRPCRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "grpc_requests_total",
+		Help:      "Number of gRPC requests handled by the provider.",
+	}, []string{"method", "outcome", "error_code"})
+	// GRPCRequestDuration observes the latency of the gRPC requests by method, outcome and error code of the mount response.
+	GRPCRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "grpc_request_duration_seconds",
+		Help:      "Latency of gRPC requests handled by the provider.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "outcome", "error_code"})
+	// APIRequestDuration observes the latency of the calls to Infisical by operation and outcome.
+	APIRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "api_request_duration_seconds",
+		Help:      "Latency of Infisical API calls.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "outcome"})
+	// APIRetries counts the retries of the calls to Infisical by operation.
+	APIRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "api_retries_total",
+		Help:      "Number of retried Infisical API calls.",
+	}, []string{"operation"})
+	// StaleSecretsServed counts the lists of secrets served from the last known good cache while Infisical is unavailable.
+	StaleSecretsServed = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stale_secrets_served_total",
+		Help:      "Number of secret lists served from the last known good cache.",
+	})
+	// Logins counts the logins to Infisical by auth method and outcome, including the ones served from the token cache.
+	Logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Number of logins to Infisical.",
+	}, []string{"auth_method", "outcome"})
+	// TokenCacheLookups counts the lookups of the token cache by result.
+	// The hit ratio is the rate of the hit lookups divided by the rate of all lookups.
+	TokenCacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "token_cache_lookups_total",
+		Help:      "Number of token cache lookups by result (hit, renewed, shared or miss).",
+	}, []string{"result"})
+	// ListSecretsCacheLookups counts the lists of secrets by whether they are served from the results listed recently (hit),
+	// shared with a concurrent list (shared), or listed from Infisical (miss).
+	ListSecretsCacheLookups = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "list_secrets_cache_lookups_total",
+		Help:      "Number of lists of secrets by result (hit, shared or miss).",
+	}, []string{"result"})
+	// SecretsServed counts the files returned by successful mounts.
+	SecretsServed = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "secrets_served_total",
+		Help:      "Number of secret files served to mounts.",
+	})
+)
+
+// Register registers all metrics of the provider.
+func Register(registerer prometheus.Registerer) error {
+	for _, collector := range []prometheus.Collector{
+		GRPCRequests,
+		GRPCRequestDuration,
+		APIRequestDuration,
+		APIRetries,
+		StaleSecretsServed,
+		Logins,
+		TokenCacheLookups,
+		ListSecretsCacheLookups,
+		SecretsServed,
+	} {
+		if err := registerer.Register(collector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Outcome returns the outcome label for the error.
+func Outcome(err error) string {
+	if err != nil {
+		return OutcomeFailure
+	}
+	return OutcomeSuccess
+}
+
+// ObserveAPIRequest observes the latency of the Infisical API call started at start.
+func ObserveAPIRequest(operation string, start time.Time, err error) {
+	APIRequestDuration.WithLabelValues(operation, Outcome(err)).Observe(time.Since(start).Seconds())
+}
+
+// UnaryServerInterceptor records the gRPC request metrics for all handlers.
+// Mount requests are labeled with the error code of the response and count the secrets served.
+func UnaryServerInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+
+	outcome := Outcome(err)
+	var errorCode string
+	if mountResponse, ok := resp.(*v1alpha1.MountResponse); ok && mountResponse != nil {
+		errorCode = mountResponse.GetError().GetCode()
+		if errorCode != "" {
+			outcome = OutcomeFailure
+		}
+		if outcome == OutcomeSuccess {
+			SecretsServed.Add(float64(len(mountResponse.GetFiles())))
+		}
+	}
+	GRPCRequests.WithLabelValues(info.FullMethod, outcome, errorCode).Inc()
+	GRPCRequestDuration.WithLabelValues(info.FullMethod, outcome, errorCode).Observe(time.Since(start).Seconds())
+
+	return resp, err
+}
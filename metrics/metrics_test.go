@@ -0,0 +1,81 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/grpc"
+	"sigs.k8s.io/secrets-store-csi-driver/provider/v1alpha1"
+)
+
+const mountMethod = "/v1alpha1.CSIDriverProvider/Mount"
+
+func TestUnaryServerInterceptorRecords(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: mountMethod}
+
+	for _, testcase := range []struct {
+		name string
+		f    func(t *testing.T)
+	}{
+		{
+			"SuccessfullyWithMountedSecrets",
+			func(t *testing.T) {
+				// Given
+				requests := testutil.ToFloat64(metrics.GRPCRequests.WithLabelValues(mountMethod, metrics.OutcomeSuccess, ""))
+				served := testutil.ToFloat64(metrics.SecretsServed)
+				handler := func(ctx context.Context, req any) (any, error) {
+					return &v1alpha1.MountResponse{
+						Error: &v1alpha1.Error{},
+						Files: []*v1alpha1.File{{Path: "DB_USERNAME"}, {Path: "DB_PASSWORD"}},
+					}, nil
+				}
+
+				// When
+				_, err := metrics.UnaryServerInterceptor(context.Background(), &v1alpha1.MountRequest{}, info, handler)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if actual := testutil.ToFloat64(metrics.GRPCRequests.WithLabelValues(mountMethod, metrics.OutcomeSuccess, "")); actual != requests+1 {
+					t.Errorf("unexpected requests: %v", actual)
+				}
+				if actual := testutil.ToFloat64(metrics.SecretsServed); actual != served+2 {
+					t.Errorf("unexpected secrets served: %v", actual)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithErrorCode",
+			func(t *testing.T) {
+				// Given
+				requests := testutil.ToFloat64(metrics.GRPCRequests.WithLabelValues(mountMethod, metrics.OutcomeFailure, "Unauthorized"))
+				served := testutil.ToFloat64(metrics.SecretsServed)
+				handler := func(ctx context.Context, req any) (any, error) {
+					return &v1alpha1.MountResponse{
+						Error: &v1alpha1.Error{Code: "Unauthorized"},
+					}, errors.New("failed to login")
+				}
+
+				// When
+				_, err := metrics.UnaryServerInterceptor(context.Background(), &v1alpha1.MountRequest{}, info, handler)
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, but got nil")
+				}
+				if actual := testutil.ToFloat64(metrics.GRPCRequests.WithLabelValues(mountMethod, metrics.OutcomeFailure, "Unauthorized")); actual != requests+1 {
+					t.Errorf("unexpected requests: %v", actual)
+				}
+				if actual := testutil.ToFloat64(metrics.SecretsServed); actual != served {
+					t.Errorf("unexpected secrets served: %v", actual)
+				}
+			},
+		},
+	} {
+		t.Run(testcase.name, testcase.f)
+	}
+}
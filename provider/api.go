@@ -0,0 +1,209 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	infisical "github.com/infisical/go-sdk"
+	"github.com/infisical/go-sdk/packages/errors"
+	"github.com/infisical/go-sdk/packages/util"
+)
+
+// Endpoints not supported by the SDK are called in the same way as the SDK does.
+// c.f. https://github.com/Infisical/go-sdk/tree/v0.3.3/packages/api
+
+// requestTimeout bounds each request of httpClient, so that a hung request is given up even without the deadline of the context.
+const requestTimeout = 30 * time.Second
+
+func newHTTPClient(config infisical.Config) *resty.Client {
+	if config.UserAgent == "" {
+		config.UserAgent = "infisical-go-sdk"
+	}
+	if config.SiteUrl == "" {
+		config.SiteUrl = util.DEFAULT_INFISICAL_API_URL
+	}
+
+	return resty.New().
+		SetHeader("User-Agent", config.UserAgent).
+		SetBaseURL(util.AppendAPIEndpoint(config.SiteUrl)).
+		SetTimeout(requestTimeout)
+}
+
+// newRequestError returns the error of ctx when the request is given up since ctx is done,
+// or RequestError of the SDK otherwise.
+func newRequestError(ctx context.Context, operation string, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return errors.NewRequestError(operation, err)
+}
+
+// RetryAfterError is an error response of the API with the Retry-After header, which the APIError of the SDK drops.
+type RetryAfterError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryAfterError) Unwrap() error {
+	return e.Err
+}
+
+// newAPIError returns the APIError of the SDK, wrapped in RetryAfterError when the response has the Retry-After header.
+func newAPIError(operation string, res *resty.Response) error {
+	err := errors.NewAPIErrorWithResponse(operation, res)
+	if retryAfter, ok := parseRetryAfter(res.Header().Get("Retry-After")); ok {
+		return &RetryAfterError{Err: err, RetryAfter: retryAfter}
+	}
+	return err
+}
+
+// parseRetryAfter parses the Retry-After header given in either seconds or an HTTP date.
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+	return 0, false
+}
+
+type renewAccessTokenRequest struct {
+	AccessToken string `json:"accessToken"`
+}
+
+const callRenewAccessTokenOperation = "CallRenewAccessToken"
+
+// c.f. https://infisical.com/docs/api-reference/endpoints/universal-auth/renew-access-token
+func callRenewAccessToken(ctx context.Context, httpClient *resty.Client, accessToken string) (infisical.MachineIdentityCredential, error) {
+	var credential infisical.MachineIdentityCredential
+
+	res, err := httpClient.R().
+		SetContext(ctx).
+		SetResult(&credential).
+		SetBody(renewAccessTokenRequest{AccessToken: accessToken}).
+		Post("/v1/auth/token/renew")
+	if err != nil {
+		return infisical.MachineIdentityCredential{}, newRequestError(ctx, callRenewAccessTokenOperation, err)
+	}
+	if res.IsError() {
+		return infisical.MachineIdentityCredential{}, newAPIError(callRenewAccessTokenOperation, res)
+	}
+
+	return credential, nil
+}
+
+// secret is a secret with the tags the SDK drops.
+type secret struct {
+	infisical.Secret
+	Tags []struct {
+		Slug string `json:"slug"`
+	} `json:"tags"`
+}
+
+func (s secret) hasTag(slug string) bool {
+	for _, tag := range s.Tags {
+		if tag.Slug == slug {
+			return true
+		}
+	}
+	return false
+}
+
+type secretImport struct {
+	SecretPath  string   `json:"secretPath"`
+	Environment string   `json:"environment"`
+	Secrets     []secret `json:"secrets"`
+}
+
+type listSecretsResponse struct {
+	Secrets []secret       `json:"secrets"`
+	Imports []secretImport `json:"imports"`
+}
+
+const callListSecretsOperation = "CallListSecrets"
+
+// callListSecrets lists secrets in the same way as the SDK,
+// but keeps secrets with the same key in different folders when listing recursively, and the tags of secrets.
+// c.f. https://infisical.com/docs/api-reference/endpoints/secrets/list
+func callListSecrets(ctx context.Context, httpClient *resty.Client, options infisical.ListSecretsOptions) (listSecretsResponse, error) {
+	var secrets listSecretsResponse
+
+	if options.SecretPath == "" {
+		options.SecretPath = "/"
+	}
+
+	res, err := httpClient.R().
+		SetContext(ctx).
+		SetResult(&secrets).
+		SetQueryParams(map[string]string{
+			"workspaceId":            options.ProjectID,
+			"workspaceSlug":          options.ProjectSlug,
+			"environment":            options.Environment,
+			"secretPath":             options.SecretPath,
+			"expandSecretReferences": fmt.Sprintf("%t", options.ExpandSecretReferences),
+			"include_imports":        fmt.Sprintf("%t", options.IncludeImports),
+			"recursive":              fmt.Sprintf("%t", options.Recursive),
+		}).
+		Get("/v3/secrets/raw")
+	if err != nil {
+		return listSecretsResponse{}, newRequestError(ctx, callListSecretsOperation, err)
+	}
+	if res.IsError() {
+		return listSecretsResponse{}, newAPIError(callListSecretsOperation, res)
+	}
+
+	return secrets, nil
+}
+
+type getSecretResponse struct {
+	Secret infisical.Secret `json:"secret"`
+}
+
+const callGetSecretOperation = "CallGetSecret"
+
+// callGetSecret retrieves a secret in the same way as the SDK, but also by the slug of the project and by the version.
+// c.f. https://infisical.com/docs/api-reference/endpoints/secrets/read
+func callGetSecret(ctx context.Context, httpClient *resty.Client, options GetSecretOptions) (infisical.Secret, error) {
+	var secret getSecretResponse
+
+	if options.SecretPath == "" {
+		options.SecretPath = "/"
+	}
+	queryParams := map[string]string{
+		"workspaceSlug":          options.ProjectSlug,
+		"environment":            options.Environment,
+		"secretPath":             options.SecretPath,
+		"expandSecretReferences": fmt.Sprintf("%t", options.ExpandSecretReferencesByServer),
+		"type":                   "shared",
+	}
+	if options.Version > 0 {
+		queryParams["version"] = fmt.Sprintf("%d", options.Version)
+	}
+
+	res, err := httpClient.R().
+		SetContext(ctx).
+		SetResult(&secret).
+		SetPathParam("secretKey", options.SecretKey).
+		SetQueryParams(queryParams).
+		Get("/v3/secrets/raw/{secretKey}")
+	if err != nil {
+		return infisical.Secret{}, newRequestError(ctx, callGetSecretOperation, err)
+	}
+	if res.IsError() {
+		return infisical.Secret{}, newAPIError(callGetSecretOperation, res)
+	}
+
+	return secret.Secret, nil
+}
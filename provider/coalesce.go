@@ -0,0 +1,231 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/metrics"
+	infisical "github.com/infisical/go-sdk"
+)
+
+// ListSecretsCoalescer shares the lists of secrets between mounts listing the same secrets with the same identity,
+// e.g. the replicas of a Deployment scheduled on the same node.
+// Concurrent lists share one call to Infisical, and successful results are reused for the TTL.
+type ListSecretsCoalescer struct {
+	mu sync.Mutex
+	// calls are the shared calls in flight, keyed by listSecretsKey
+	calls map[string]*coalescedCall
+	// results are the successful results, keyed by listSecretsKey
+	results map[string]coalescedResult
+	ttl     time.Duration
+}
+
+type coalescedResult struct {
+	secrets  []infisical.Secret
+	listedAt time.Time
+}
+
+// coalescedCall is a call shared by mounts.
+// It is made on its own context, so that a mount leaving does not fail the others,
+// which is cancelled when all the mounts leave or the latest deadline of them is exceeded.
+type coalescedCall struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+	// waiters are the number of the mounts waiting for the call
+	waiters int
+	// deadline is the latest deadline of the waiters, and timer cancels the call at it.
+	// timer is nil when any of the waiters has no deadline.
+	deadline time.Time
+	timer    *time.Timer
+	secrets  []infisical.Secret
+	err      error
+}
+
+// NewListSecretsCoalescer returns ListSecretsCoalescer reusing results for ttl.
+// Only concurrent lists are shared when ttl is zero.
+func NewListSecretsCoalescer(ttl time.Duration) *ListSecretsCoalescer {
+	return &ListSecretsCoalescer{
+		calls:   map[string]*coalescedCall{},
+		results: map[string]coalescedResult{},
+		ttl:     ttl,
+	}
+}
+
+// get returns the result of the key listed within the TTL, and forgets expired results.
+func (c *ListSecretsCoalescer) get(key string) ([]infisical.Secret, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	current := now()
+	for k, result := range c.results {
+		if current.Sub(result.listedAt) >= c.ttl {
+			delete(c.results, k)
+		}
+	}
+	result, ok := c.results[key]
+	return result.secrets, ok
+}
+
+func (c *ListSecretsCoalescer) set(key string, secrets []infisical.Secret) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.results[key] = coalescedResult{
+		secrets:  secrets,
+		listedAt: now(),
+	}
+}
+
+// join returns the call of the key in flight with ctx waiting for it, or starts a new call with list when there is none.
+// started reports whether the call is started by ctx.
+func (c *ListSecretsCoalescer) join(ctx context.Context, key string, list func(ctx context.Context) ([]infisical.Secret, error)) (call *coalescedCall, started bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	call, ok := c.calls[key]
+	if ok && call.ctx.Err() == nil {
+		call.waiters++
+		if call.timer != nil {
+			if deadline, ok := ctx.Deadline(); !ok {
+				call.timer.Stop()
+				call.timer = nil
+			} else if deadline.After(call.deadline) {
+				call.deadline = deadline
+				call.timer.Reset(time.Until(deadline))
+			}
+		}
+		return call, false
+	}
+
+	callCtx, cancel := context.WithCancel(context.Background())
+	call = &coalescedCall{
+		ctx:     callCtx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		waiters: 1,
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		call.deadline = deadline
+		call.timer = time.AfterFunc(time.Until(deadline), cancel)
+	}
+	c.calls[key] = call
+
+	go func() {
+		secrets, err := list(callCtx)
+
+		c.mu.Lock()
+		if c.calls[key] == call {
+			delete(c.calls, key)
+		}
+		if call.timer != nil {
+			call.timer.Stop()
+		}
+		c.mu.Unlock()
+		cancel()
+
+		call.secrets, call.err = secrets, err
+		close(call.done)
+	}()
+	return call, true
+}
+
+// leave stops ctx waiting for the call, and cancels the call when no mounts wait for it.
+func (c *ListSecretsCoalescer) leave(call *coalescedCall) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	call.waiters--
+	if call.waiters == 0 {
+		call.cancel()
+	}
+}
+
+// coalescingInfisicalClient decorates InfisicalClient to share the lists of secrets through ListSecretsCoalescer.
+// A shared call is made by the client of the mount which starts it, bound to the latest deadline of the mounts sharing it,
+// and its failure is returned to all mounts sharing it.
+// Stale secrets served by NewLastKnownGoodInfisicalClient are shared only with concurrent mounts, and not reused for the TTL.
+type coalescingInfisicalClient struct {
+	InfisicalClient
+	ctx       context.Context
+	coalescer *ListSecretsCoalescer
+	siteUrl   string
+	identity  string
+}
+
+// NewCoalescingInfisicalClient returns InfisicalClient sharing the lists of secrets from the site by the identity with other clients
+// while ctx is not done.
+// The identity must identify the credentials client is logged in with.
+func NewCoalescingInfisicalClient(ctx context.Context, client InfisicalClient, coalescer *ListSecretsCoalescer, siteUrl, identity string) InfisicalClient {
+	return &coalescingInfisicalClient{
+		InfisicalClient: client,
+		ctx:             ctx,
+		coalescer:       coalescer,
+		siteUrl:         siteUrl,
+		identity:        identity,
+	}
+}
+
+func (c *coalescingInfisicalClient) WithContext(ctx context.Context) InfisicalClient {
+	return NewCoalescingInfisicalClient(ctx, c.InfisicalClient.WithContext(ctx), c.coalescer, c.siteUrl, c.identity)
+}
+
+func (c *coalescingInfisicalClient) ListSecrets(options ListSecretsOptions) ([]infisical.Secret, error) {
+	key, err := listSecretsKey(c.siteUrl, c.identity, options)
+	if err != nil {
+		return c.InfisicalClient.ListSecrets(options)
+	}
+	if secrets, ok := c.coalescer.get(key); ok {
+		metrics.ListSecretsCacheLookups.WithLabelValues(metrics.CacheHit).Inc()
+		return slices.Clone(secrets), nil
+	}
+
+	call, started := c.coalescer.join(c.ctx, key, func(ctx context.Context) ([]infisical.Secret, error) {
+		secrets, stale, err := listSecrets(c.InfisicalClient.WithContext(ctx), options)
+		if err == nil && !stale {
+			c.coalescer.set(key, secrets)
+		}
+		return secrets, err
+	})
+	if started {
+		metrics.ListSecretsCacheLookups.WithLabelValues(metrics.CacheMiss).Inc()
+	} else {
+		metrics.ListSecretsCacheLookups.WithLabelValues(metrics.CacheShared).Inc()
+	}
+	select {
+	case <-call.done:
+	case <-c.ctx.Done():
+		c.coalescer.leave(call)
+		if errors.Is(c.ctx.Err(), context.DeadlineExceeded) {
+			// the client fails at once with the deadline, which serves the last known good secrets of NewLastKnownGoodInfisicalClient
+			return c.InfisicalClient.ListSecrets(options)
+		}
+		return nil, c.ctx.Err()
+	}
+	if call.err != nil {
+		return nil, call.err
+	}
+	return slices.Clone(call.secrets), nil
+}
+
+// staleSecretsLister is implemented by InfisicalClient which may serve stale secrets.
+type staleSecretsLister interface {
+	// listSecrets lists the secrets, and reports whether they are stale ones instead of the ones listed from Infisical.
+	listSecrets(options ListSecretsOptions) ([]infisical.Secret, bool, error)
+}
+
+// listSecrets lists the secrets with client, and reports whether they are stale.
+func listSecrets(client InfisicalClient, options ListSecretsOptions) ([]infisical.Secret, bool, error) {
+	if lister, ok := client.(staleSecretsLister); ok {
+		return lister.listSecrets(options)
+	}
+	secrets, err := client.ListSecrets(options)
+	return secrets, false, err
+}
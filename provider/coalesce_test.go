@@ -0,0 +1,301 @@
+package provider_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/provider"
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/provider/mock_provider"
+	infisical "github.com/infisical/go-sdk"
+	"github.com/infisical/go-sdk/packages/models"
+	"go.uber.org/mock/gomock"
+)
+
+func TestCoalescingInfisicalClientSharesSecrets(t *testing.T) {
+	var (
+		mockInfisicalClient *mock_provider.MockInfisicalClient
+		coalescer           *provider.ListSecretsCoalescer
+		current             time.Time
+		options             provider.ListSecretsOptions
+	)
+
+	for _, testcase := range []struct {
+		name string
+		f    func(t *testing.T)
+	}{
+		{
+			"SuccessfullyWithConcurrentLists",
+			func(t *testing.T) {
+				// Given
+				coalescer = provider.NewListSecretsCoalescer(0)
+				release := make(chan struct{})
+				mockInfisicalClient.EXPECT().ListSecrets(options).DoAndReturn(func(provider.ListSecretsOptions) ([]models.Secret, error) {
+					<-release
+					return []models.Secret{{SecretKey: "DB_PASSWORD"}}, nil
+				})
+				var wg sync.WaitGroup
+				results := make([][]models.Secret, 10)
+				errs := make([]error, 10)
+
+				// When
+				for i := range results {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						client := provider.NewCoalescingInfisicalClient(context.Background(), mockInfisicalClient, coalescer, "https://app.infisical.com", "test-identity")
+						results[i], errs[i] = client.ListSecrets(options)
+					}()
+				}
+				time.Sleep(100 * time.Millisecond)
+				close(release)
+				wg.Wait()
+
+				// Then
+				for i := range results {
+					if errs[i] != nil {
+						t.Errorf("unexpected error: %s", errs[i])
+					}
+					if len(results[i]) != 1 {
+						t.Errorf("unexpected secrets: %v", results[i])
+					}
+				}
+			},
+		},
+		{
+			"SuccessfullyWithinTTL",
+			func(t *testing.T) {
+				// Given
+				client := provider.NewCoalescingInfisicalClient(context.Background(), mockInfisicalClient, coalescer, "https://app.infisical.com", "test-identity")
+				mockInfisicalClient.EXPECT().ListSecrets(options).Return([]models.Secret{{SecretKey: "DB_PASSWORD"}}, nil)
+				_, _ = client.ListSecrets(options)
+				current = current.Add(4 * time.Second)
+
+				// When
+				secrets, err := client.ListSecrets(options)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(secrets) != 1 {
+					t.Errorf("unexpected secrets: %v", secrets)
+				}
+			},
+		},
+		{
+			"SuccessfullyAfterTTL",
+			func(t *testing.T) {
+				// Given
+				client := provider.NewCoalescingInfisicalClient(context.Background(), mockInfisicalClient, coalescer, "https://app.infisical.com", "test-identity")
+				mockInfisicalClient.EXPECT().ListSecrets(options).Return([]models.Secret{{SecretKey: "DB_PASSWORD"}}, nil).Times(2)
+				_, _ = client.ListSecrets(options)
+				current = current.Add(5 * time.Second)
+
+				// When
+				_, err := client.ListSecrets(options)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithoutSharingWithAnotherIdentity",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClient.EXPECT().ListSecrets(options).Return([]models.Secret{{SecretKey: "DB_PASSWORD"}}, nil)
+				_, _ = provider.NewCoalescingInfisicalClient(context.Background(), mockInfisicalClient, coalescer, "https://app.infisical.com", "test-identity").ListSecrets(options)
+				client := provider.NewCoalescingInfisicalClient(context.Background(), mockInfisicalClient, coalescer, "https://app.infisical.com", "another-identity")
+				mockInfisicalClient.EXPECT().ListSecrets(options).Return([]models.Secret{}, nil)
+
+				// When
+				secrets, err := client.ListSecrets(options)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(secrets) != 0 {
+					t.Errorf("unexpected secrets: %v", secrets)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithoutSharingWithAnotherOptions",
+			func(t *testing.T) {
+				// Given
+				client := provider.NewCoalescingInfisicalClient(context.Background(), mockInfisicalClient, coalescer, "https://app.infisical.com", "test-identity")
+				another := options
+				another.TagSlugs = []string{"shared"}
+				mockInfisicalClient.EXPECT().ListSecrets(options).Return([]models.Secret{{SecretKey: "DB_PASSWORD"}}, nil)
+				mockInfisicalClient.EXPECT().ListSecrets(another).Return([]models.Secret{}, nil)
+				_, _ = client.ListSecrets(options)
+
+				// When
+				secrets, err := client.ListSecrets(another)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(secrets) != 0 {
+					t.Errorf("unexpected secrets: %v", secrets)
+				}
+			},
+		},
+		{
+			"SuccessfullyAfterFailure",
+			func(t *testing.T) {
+				// Given
+				client := provider.NewCoalescingInfisicalClient(context.Background(), mockInfisicalClient, coalescer, "https://app.infisical.com", "test-identity")
+				unavailable := &infisical.APIError{StatusCode: http.StatusServiceUnavailable}
+				gomock.InOrder(
+					mockInfisicalClient.EXPECT().ListSecrets(options).Return(nil, unavailable),
+					mockInfisicalClient.EXPECT().ListSecrets(options).Return([]models.Secret{{SecretKey: "DB_PASSWORD"}}, nil),
+				)
+				if _, err := client.ListSecrets(options); !errors.Is(err, unavailable) {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				// When
+				secrets, err := client.ListSecrets(options)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(secrets) != 1 {
+					t.Errorf("unexpected secrets: %v", secrets)
+				}
+			},
+		},
+		{
+			"SuccessfullyWhenMountStartingListLeaves",
+			func(t *testing.T) {
+				// Given
+				release := make(chan struct{})
+				mockInfisicalClient.EXPECT().ListSecrets(options).DoAndReturn(func(provider.ListSecretsOptions) ([]models.Secret, error) {
+					<-release
+					return []models.Secret{{SecretKey: "DB_PASSWORD"}}, nil
+				})
+				ctx, cancel := context.WithCancel(context.Background())
+				left := make(chan error)
+				go func() {
+					_, err := provider.NewCoalescingInfisicalClient(ctx, mockInfisicalClient, coalescer, "https://app.infisical.com", "test-identity").ListSecrets(options)
+					left <- err
+				}()
+				time.Sleep(50 * time.Millisecond)
+				client := provider.NewCoalescingInfisicalClient(context.Background(), mockInfisicalClient, coalescer, "https://app.infisical.com", "test-identity")
+				listed := make(chan struct{})
+				var secrets []models.Secret
+				var err error
+
+				// When
+				go func() {
+					defer close(listed)
+					secrets, err = client.ListSecrets(options)
+				}()
+				time.Sleep(50 * time.Millisecond)
+				cancel()
+				if err := <-left; !errors.Is(err, context.Canceled) {
+					t.Errorf("unexpected error of the mount leaving: %v", err)
+				}
+				close(release)
+				<-listed
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(secrets) != 1 {
+					t.Errorf("unexpected secrets: %v", secrets)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithoutReusingStaleSecrets",
+			func(t *testing.T) {
+				// Given
+				cache, _ := provider.NewLastKnownGoodCache(time.Hour, "", nil)
+				lastKnownGood := provider.NewLastKnownGoodInfisicalClient(mockInfisicalClient, cache, "https://app.infisical.com", "test-identity")
+				client := provider.NewCoalescingInfisicalClient(context.Background(), lastKnownGood, coalescer, "https://app.infisical.com", "test-identity")
+				gomock.InOrder(
+					mockInfisicalClient.EXPECT().ListSecrets(options).Return([]models.Secret{{SecretKey: "DB_PASSWORD", SecretValue: "stale"}}, nil),
+					mockInfisicalClient.EXPECT().ListSecrets(options).Return(nil, &infisical.APIError{StatusCode: http.StatusServiceUnavailable}),
+					mockInfisicalClient.EXPECT().ListSecrets(options).Return([]models.Secret{{SecretKey: "DB_PASSWORD", SecretValue: "fresh"}}, nil),
+				)
+				_, _ = client.ListSecrets(options)
+				current = current.Add(5 * time.Second)
+				if secrets, err := client.ListSecrets(options); err != nil || len(secrets) != 1 || secrets[0].SecretValue != "stale" {
+					t.Fatalf("unexpected stale secrets: %v, error: %v", secrets, err)
+				}
+
+				// When
+				secrets, err := client.ListSecrets(options)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(secrets) != 1 || secrets[0].SecretValue != "fresh" {
+					t.Errorf("unexpected secrets: %v", secrets)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithLastKnownGoodSecretsWhenDeadlineExceeded",
+			func(t *testing.T) {
+				// Given
+				cache, _ := provider.NewLastKnownGoodCache(time.Hour, "", nil)
+				lastKnownGood := provider.NewLastKnownGoodInfisicalClient(mockInfisicalClient, cache, "https://app.infisical.com", "test-identity")
+				release := make(chan struct{})
+				gomock.InOrder(
+					mockInfisicalClient.EXPECT().ListSecrets(options).Return([]models.Secret{{SecretKey: "DB_PASSWORD", SecretValue: "stale"}}, nil),
+					mockInfisicalClient.EXPECT().ListSecrets(options).DoAndReturn(func(provider.ListSecretsOptions) ([]models.Secret, error) {
+						<-release
+						return nil, context.Canceled
+					}),
+					mockInfisicalClient.EXPECT().ListSecrets(options).Return(nil, context.DeadlineExceeded),
+				)
+				_, _ = provider.NewCoalescingInfisicalClient(context.Background(), lastKnownGood, coalescer, "https://app.infisical.com", "test-identity").ListSecrets(options)
+				current = current.Add(5 * time.Second)
+				ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+				defer cancel()
+				client := provider.NewCoalescingInfisicalClient(ctx, lastKnownGood, coalescer, "https://app.infisical.com", "test-identity")
+
+				// When
+				secrets, err := client.ListSecrets(options)
+				close(release)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(secrets) != 1 || secrets[0].SecretValue != "stale" {
+					t.Errorf("unexpected secrets: %v", secrets)
+				}
+			},
+		},
+	} {
+		mockInfisicalClient = mock_provider.NewMockInfisicalClient(gomock.NewController(t))
+		mockInfisicalClient.EXPECT().WithContext(gomock.Any()).Return(mockInfisicalClient).AnyTimes()
+		coalescer = provider.NewListSecretsCoalescer(5 * time.Second)
+		current = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		restore := provider.SetNow(func() time.Time { return current })
+		options = provider.ListSecretsOptions{
+			ListSecretsOptions: infisical.ListSecretsOptions{
+				ProjectSlug: "test-project",
+				Environment: "dev",
+				SecretPath:  "/",
+			},
+		}
+
+		t.Run(testcase.name, testcase.f)
+		restore()
+	}
+}
@@ -0,0 +1,11 @@
+package provider
+
+import "time"
+
+func SetNow(f func() time.Time) (restore func()) {
+	original := now
+	now = f
+	return func() {
+		now = original
+	}
+}
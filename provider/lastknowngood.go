@@ -0,0 +1,347 @@
+package provider
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/metrics"
+	infisical "github.com/infisical/go-sdk"
+)
+
+var now = time.Now
+
+// LastKnownGoodCache holds the secrets last listed successfully, to be served while Infisical is unavailable.
+// Entries are keyed by the site, the identity logged in with, and the options of the list,
+// so that secrets listed by an identity are never served to another one.
+type LastKnownGoodCache struct {
+	mu      sync.Mutex
+	entries map[string]lastKnownGoodEntry
+	// sweeps delete the entries from memory and the disk when their grace period ends
+	sweeps map[string]*lastKnownGoodSweep
+	// gracePeriod is how long entries are served after they are stored
+	gracePeriod time.Duration
+	// dir persists the entries encrypted with aead across restarts, when it is not empty
+	dir  string
+	aead cipher.AEAD
+}
+
+type lastKnownGoodEntry struct {
+	Secrets  []infisical.Secret `json:"secrets"`
+	StoredAt time.Time          `json:"storedAt"`
+}
+
+// lastKnownGoodSweep deletes an entry when its grace period ends.
+type lastKnownGoodSweep struct {
+	timer *time.Timer
+}
+
+// NewLastKnownGoodCache returns LastKnownGoodCache serving entries for gracePeriod.
+// Entries are kept only in memory when dir is empty.
+// Otherwise they are also stored in dir encrypted with AES-GCM whose key is derived from key, which must not be empty,
+// and the entries left in dir are swept when their grace period ends, judged by the modification time of the files.
+func NewLastKnownGoodCache(gracePeriod time.Duration, dir string, key []byte) (*LastKnownGoodCache, error) {
+	c := &LastKnownGoodCache{
+		entries:     map[string]lastKnownGoodEntry{},
+		sweeps:      map[string]*lastKnownGoodSweep{},
+		gracePeriod: gracePeriod,
+		dir:         dir,
+	}
+	if dir == "" {
+		return c, nil
+	}
+
+	if len(key) == 0 {
+		return nil, errors.New("key is required to store secrets on disk")
+	}
+	digest := sha256.Sum256(key)
+	block, err := aes.NewCipher(digest[:])
+	if err != nil {
+		return nil, err
+	}
+	if c.aead, err = cipher.NewGCM(block); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, file := range files {
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		age := now().Sub(info.ModTime())
+		if strings.Contains(file.Name(), ".") || age >= gracePeriod {
+			// temporary files are left by interrupted stores
+			_ = os.Remove(filepath.Join(dir, file.Name()))
+			continue
+		}
+		c.schedule(file.Name(), gracePeriod-age)
+	}
+	return c, nil
+}
+
+// get returns the entry of the key stored within the grace period, loading it from the disk if it is not in memory.
+func (c *LastKnownGoodCache) get(key string) (lastKnownGoodEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok && c.dir != "" {
+		var err error
+		if entry, ok, err = c.load(key); err != nil {
+			slog.Warn("failed to load last known good secrets", "error", err)
+		}
+	}
+	if !ok {
+		return lastKnownGoodEntry{}, false
+	}
+	if now().Sub(entry.StoredAt) > c.gracePeriod {
+		c.delete(key)
+		return lastKnownGoodEntry{}, false
+	}
+	c.entries[key] = entry
+	return entry, true
+}
+
+func (c *LastKnownGoodCache) set(key string, secrets []infisical.Secret) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := lastKnownGoodEntry{
+		Secrets:  secrets,
+		StoredAt: now(),
+	}
+	c.entries[key] = entry
+	if c.dir != "" {
+		if err := c.store(key, entry); err != nil {
+			slog.Warn("failed to store last known good secrets", "error", err)
+		}
+	}
+	c.schedule(key, c.gracePeriod)
+}
+
+// schedule sweeps the entry of the key after the duration, replacing the sweep scheduled before.
+// It must be called with mu locked.
+func (c *LastKnownGoodCache) schedule(key string, after time.Duration) {
+	if sweep, ok := c.sweeps[key]; ok {
+		sweep.timer.Stop()
+	}
+	sweep := &lastKnownGoodSweep{}
+	sweep.timer = time.AfterFunc(after, func() {
+		c.sweep(key, sweep)
+	})
+	c.sweeps[key] = sweep
+}
+
+// sweep deletes the entry of the key unless another sweep is scheduled since the entry is stored again.
+func (c *LastKnownGoodCache) sweep(key string, sweep *lastKnownGoodSweep) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.sweeps[key] != sweep {
+		return
+	}
+	c.delete(key)
+}
+
+func (c *LastKnownGoodCache) delete(key string) {
+	if sweep, ok := c.sweeps[key]; ok {
+		sweep.timer.Stop()
+		delete(c.sweeps, key)
+	}
+	delete(c.entries, key)
+	if c.dir != "" {
+		_ = os.Remove(filepath.Join(c.dir, key))
+	}
+}
+
+// load decrypts the entry of the key stored on the disk.
+// The key is authenticated as well, so that an entry moved to the file of another key is rejected.
+func (c *LastKnownGoodCache) load(key string) (lastKnownGoodEntry, bool, error) {
+	sealed, err := os.ReadFile(filepath.Join(c.dir, key))
+	if errors.Is(err, os.ErrNotExist) {
+		return lastKnownGoodEntry{}, false, nil
+	}
+	if err != nil {
+		return lastKnownGoodEntry{}, false, err
+	}
+	if len(sealed) < c.aead.NonceSize() {
+		return lastKnownGoodEntry{}, false, fmt.Errorf("entry %s is truncated", key)
+	}
+	nonce, ciphertext := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, []byte(key))
+	if err != nil {
+		return lastKnownGoodEntry{}, false, fmt.Errorf("failed to decrypt entry %s: %w", key, err)
+	}
+	var entry lastKnownGoodEntry
+	if err := json.Unmarshal(plaintext, &entry); err != nil {
+		return lastKnownGoodEntry{}, false, err
+	}
+	return entry, true, nil
+}
+
+// store encrypts the entry of the key and replaces the file of the key with it.
+func (c *LastKnownGoodCache) store(key string, entry lastKnownGoodEntry) error {
+	plaintext, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	sealed := c.aead.Seal(nonce, nonce, plaintext, []byte(key))
+
+	file, err := os.CreateTemp(c.dir, key+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(sealed); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), filepath.Join(c.dir, key))
+}
+
+// lastKnownGoodInfisicalClient decorates InfisicalClient to store the listed secrets in LastKnownGoodCache,
+// and to serve them when listing fails while Infisical is unavailable.
+// Failures rejecting the identity or the request are returned as they are.
+type lastKnownGoodInfisicalClient struct {
+	InfisicalClient
+	cache    *LastKnownGoodCache
+	siteUrl  string
+	identity string
+}
+
+// NewLastKnownGoodInfisicalClient returns InfisicalClient serving the secrets last listed from the site by the identity
+// when Infisical is unavailable.
+// The identity must identify the credentials client is logged in with.
+func NewLastKnownGoodInfisicalClient(client InfisicalClient, cache *LastKnownGoodCache, siteUrl, identity string) InfisicalClient {
+	return &lastKnownGoodInfisicalClient{
+		InfisicalClient: client,
+		cache:           cache,
+		siteUrl:         siteUrl,
+		identity:        identity,
+	}
+}
+
+func (c *lastKnownGoodInfisicalClient) WithContext(ctx context.Context) InfisicalClient {
+	return NewLastKnownGoodInfisicalClient(c.InfisicalClient.WithContext(ctx), c.cache, c.siteUrl, c.identity)
+}
+
+func (c *lastKnownGoodInfisicalClient) ListSecrets(options ListSecretsOptions) ([]infisical.Secret, error) {
+	secrets, _, err := c.listSecrets(options)
+	return secrets, err
+}
+
+func (c *lastKnownGoodInfisicalClient) listSecrets(options ListSecretsOptions) ([]infisical.Secret, bool, error) {
+	secrets, err := c.InfisicalClient.ListSecrets(options)
+	key, keyErr := listSecretsKey(c.siteUrl, c.identity, options)
+	if keyErr != nil {
+		return secrets, false, err
+	}
+	if err == nil {
+		c.cache.set(key, secrets)
+		return secrets, false, nil
+	}
+	if !IsUnavailable(err) {
+		return nil, false, err
+	}
+
+	entry, ok := c.cache.get(key)
+	if !ok {
+		return nil, false, err
+	}
+	metrics.StaleSecretsServed.Inc()
+	slog.Warn("serving last known good secrets",
+		"siteUrl", c.siteUrl,
+		"project", options.ProjectSlug,
+		"environment", options.Environment,
+		"secretPath", options.SecretPath,
+		"age", now().Sub(entry.StoredAt),
+		"error", err,
+	)
+	return entry.Secrets, true, nil
+}
+
+// IsUnavailable reports whether the error shows Infisical is unavailable rather than rejecting the request.
+// Exceeding the deadline does as well, since Infisical does not respond in time,
+// and callers should leave time to serve the last known good secrets after the deadline of the calls.
+func IsUnavailable(err error) bool {
+	return isRetryable(err) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// unavailableInfisicalClient fails all calls with the error of the login, for mounts failing to log in while Infisical is unavailable.
+type unavailableInfisicalClient struct {
+	err error
+}
+
+// NewUnavailableInfisicalClient returns InfisicalClient whose calls fail with err.
+// Decorated by NewLastKnownGoodInfisicalClient, it serves the secrets last listed by the identity the mount fails to log in as.
+func NewUnavailableInfisicalClient(err error) InfisicalClient {
+	return &unavailableInfisicalClient{err: err}
+}
+
+func (c *unavailableInfisicalClient) WithContext(context.Context) InfisicalClient {
+	return c
+}
+
+func (c *unavailableInfisicalClient) SetAccessToken(string) {}
+
+func (c *unavailableInfisicalClient) UniversalAuthLogin(string, string) (infisical.MachineIdentityCredential, error) {
+	return infisical.MachineIdentityCredential{}, c.err
+}
+
+func (c *unavailableInfisicalClient) KubernetesAuthLogin(string, string) (infisical.MachineIdentityCredential, error) {
+	return infisical.MachineIdentityCredential{}, c.err
+}
+
+func (c *unavailableInfisicalClient) OidcAuthLogin(string, string) (infisical.MachineIdentityCredential, error) {
+	return infisical.MachineIdentityCredential{}, c.err
+}
+
+func (c *unavailableInfisicalClient) AwsIamAuthLogin(string) (infisical.MachineIdentityCredential, error) {
+	return infisical.MachineIdentityCredential{}, c.err
+}
+
+func (c *unavailableInfisicalClient) GcpIdTokenAuthLogin(string) (infisical.MachineIdentityCredential, error) {
+	return infisical.MachineIdentityCredential{}, c.err
+}
+
+func (c *unavailableInfisicalClient) AzureAuthLogin(string, string) (infisical.MachineIdentityCredential, error) {
+	return infisical.MachineIdentityCredential{}, c.err
+}
+
+func (c *unavailableInfisicalClient) RenewAccessToken(string) (infisical.MachineIdentityCredential, error) {
+	return infisical.MachineIdentityCredential{}, c.err
+}
+
+func (c *unavailableInfisicalClient) ListSecrets(ListSecretsOptions) ([]infisical.Secret, error) {
+	return nil, c.err
+}
+
+func (c *unavailableInfisicalClient) GetSecret(GetSecretOptions) (infisical.Secret, error) {
+	return infisical.Secret{}, c.err
+}
@@ -0,0 +1,280 @@
+package provider_test
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/metrics"
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/provider"
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/provider/mock_provider"
+	infisical "github.com/infisical/go-sdk"
+	"github.com/infisical/go-sdk/packages/models"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/mock/gomock"
+)
+
+func TestLastKnownGoodInfisicalClientServesStaleSecrets(t *testing.T) {
+	var (
+		mockInfisicalClient *mock_provider.MockInfisicalClient
+		cache               *provider.LastKnownGoodCache
+		current             time.Time
+		options             provider.ListSecretsOptions
+		unavailable         error
+	)
+
+	for _, testcase := range []struct {
+		name string
+		f    func(t *testing.T)
+	}{
+		{
+			"SuccessfullyWhileUnavailable",
+			func(t *testing.T) {
+				// Given
+				client := provider.NewLastKnownGoodInfisicalClient(mockInfisicalClient, cache, "https://app.infisical.com", "test-identity")
+				gomock.InOrder(
+					mockInfisicalClient.EXPECT().ListSecrets(options).Return([]models.Secret{{SecretKey: "DB_PASSWORD", SecretValue: "password"}}, nil),
+					mockInfisicalClient.EXPECT().ListSecrets(options).Return(nil, unavailable),
+				)
+				_, _ = client.ListSecrets(options)
+				current = current.Add(time.Minute)
+				served := testutil.ToFloat64(metrics.StaleSecretsServed)
+
+				// When
+				secrets, err := client.ListSecrets(options)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(secrets) != 1 || secrets[0].SecretValue != "password" {
+					t.Errorf("unexpected secrets: %v", secrets)
+				}
+				if actual := testutil.ToFloat64(metrics.StaleSecretsServed); actual != served+1 {
+					t.Errorf("unexpected stale secrets served: %v", actual)
+				}
+			},
+		},
+		{
+			"FailedWithoutStaleSecretsOfAnotherIdentity",
+			func(t *testing.T) {
+				// Given
+				gomock.InOrder(
+					mockInfisicalClient.EXPECT().ListSecrets(options).Return([]models.Secret{{SecretKey: "DB_PASSWORD"}}, nil),
+					mockInfisicalClient.EXPECT().ListSecrets(options).Return(nil, unavailable),
+				)
+				_, _ = provider.NewLastKnownGoodInfisicalClient(mockInfisicalClient, cache, "https://app.infisical.com", "test-identity").ListSecrets(options)
+				client := provider.NewLastKnownGoodInfisicalClient(mockInfisicalClient, cache, "https://app.infisical.com", "another-identity")
+
+				// When
+				_, err := client.ListSecrets(options)
+
+				// Then
+				if !errors.Is(err, unavailable) {
+					t.Errorf("unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			"FailedWithoutStaleSecretsOfAnotherFolder",
+			func(t *testing.T) {
+				// Given
+				client := provider.NewLastKnownGoodInfisicalClient(mockInfisicalClient, cache, "https://app.infisical.com", "test-identity")
+				another := options
+				another.SecretPath = "/another"
+				gomock.InOrder(
+					mockInfisicalClient.EXPECT().ListSecrets(options).Return([]models.Secret{{SecretKey: "DB_PASSWORD"}}, nil),
+					mockInfisicalClient.EXPECT().ListSecrets(another).Return(nil, unavailable),
+				)
+				_, _ = client.ListSecrets(options)
+
+				// When
+				_, err := client.ListSecrets(another)
+
+				// Then
+				if !errors.Is(err, unavailable) {
+					t.Errorf("unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			"FailedAfterGracePeriod",
+			func(t *testing.T) {
+				// Given
+				client := provider.NewLastKnownGoodInfisicalClient(mockInfisicalClient, cache, "https://app.infisical.com", "test-identity")
+				gomock.InOrder(
+					mockInfisicalClient.EXPECT().ListSecrets(options).Return([]models.Secret{{SecretKey: "DB_PASSWORD"}}, nil),
+					mockInfisicalClient.EXPECT().ListSecrets(options).Return(nil, unavailable),
+				)
+				_, _ = client.ListSecrets(options)
+				current = current.Add(time.Hour + time.Second)
+
+				// When
+				_, err := client.ListSecrets(options)
+
+				// Then
+				if !errors.Is(err, unavailable) {
+					t.Errorf("unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			"FailedWithoutStaleSecretsWhenRejected",
+			func(t *testing.T) {
+				// Given
+				client := provider.NewLastKnownGoodInfisicalClient(mockInfisicalClient, cache, "https://app.infisical.com", "test-identity")
+				forbidden := &infisical.APIError{StatusCode: http.StatusForbidden}
+				gomock.InOrder(
+					mockInfisicalClient.EXPECT().ListSecrets(options).Return([]models.Secret{{SecretKey: "DB_PASSWORD"}}, nil),
+					mockInfisicalClient.EXPECT().ListSecrets(options).Return(nil, forbidden),
+				)
+				_, _ = client.ListSecrets(options)
+
+				// When
+				_, err := client.ListSecrets(options)
+
+				// Then
+				if !errors.Is(err, forbidden) {
+					t.Errorf("unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyFromDiskAfterRestart",
+			func(t *testing.T) {
+				// Given
+				dir := t.TempDir()
+				cache, _ := provider.NewLastKnownGoodCache(time.Hour, dir, []byte("test-key"))
+				gomock.InOrder(
+					mockInfisicalClient.EXPECT().ListSecrets(options).Return([]models.Secret{{SecretKey: "DB_PASSWORD", SecretValue: "password"}}, nil),
+					mockInfisicalClient.EXPECT().ListSecrets(options).Return(nil, unavailable),
+				)
+				_, _ = provider.NewLastKnownGoodInfisicalClient(mockInfisicalClient, cache, "https://app.infisical.com", "test-identity").ListSecrets(options)
+				restarted, err := provider.NewLastKnownGoodCache(time.Hour, dir, []byte("test-key"))
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				client := provider.NewLastKnownGoodInfisicalClient(mockInfisicalClient, restarted, "https://app.infisical.com", "test-identity")
+
+				// When
+				secrets, err := client.ListSecrets(options)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(secrets) != 1 || secrets[0].SecretValue != "password" {
+					t.Errorf("unexpected secrets: %v", secrets)
+				}
+			},
+		},
+		{
+			"FailedFromDiskWithAnotherKey",
+			func(t *testing.T) {
+				// Given
+				dir := t.TempDir()
+				cache, _ := provider.NewLastKnownGoodCache(time.Hour, dir, []byte("test-key"))
+				gomock.InOrder(
+					mockInfisicalClient.EXPECT().ListSecrets(options).Return([]models.Secret{{SecretKey: "DB_PASSWORD"}}, nil),
+					mockInfisicalClient.EXPECT().ListSecrets(options).Return(nil, unavailable),
+				)
+				_, _ = provider.NewLastKnownGoodInfisicalClient(mockInfisicalClient, cache, "https://app.infisical.com", "test-identity").ListSecrets(options)
+				restarted, _ := provider.NewLastKnownGoodCache(time.Hour, dir, []byte("another-key"))
+				client := provider.NewLastKnownGoodInfisicalClient(mockInfisicalClient, restarted, "https://app.infisical.com", "test-identity")
+
+				// When
+				_, err := client.ListSecrets(options)
+
+				// Then
+				if !errors.Is(err, unavailable) {
+					t.Errorf("unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			"SuccessfullySweepingExpiredEntries",
+			func(t *testing.T) {
+				// Given
+				restoreNow := provider.SetNow(time.Now)
+				defer restoreNow()
+				dir := t.TempDir()
+				cache, _ := provider.NewLastKnownGoodCache(50*time.Millisecond, dir, []byte("test-key"))
+				mockInfisicalClient.EXPECT().ListSecrets(options).Return([]models.Secret{{SecretKey: "DB_PASSWORD"}}, nil)
+				_, _ = provider.NewLastKnownGoodInfisicalClient(mockInfisicalClient, cache, "https://app.infisical.com", "test-identity").ListSecrets(options)
+				if files, _ := os.ReadDir(dir); len(files) != 1 {
+					t.Fatalf("unexpected files: %v", files)
+				}
+
+				// When
+				deadline := time.Now().Add(5 * time.Second)
+				files, _ := os.ReadDir(dir)
+				for len(files) > 0 && time.Now().Before(deadline) {
+					time.Sleep(10 * time.Millisecond)
+					files, _ = os.ReadDir(dir)
+				}
+
+				// Then
+				if len(files) != 0 {
+					t.Errorf("unexpected files: %v", files)
+				}
+			},
+		},
+		{
+			"SuccessfullySweepingExpiredEntriesOnRestart",
+			func(t *testing.T) {
+				// Given
+				dir := t.TempDir()
+				cache, _ := provider.NewLastKnownGoodCache(time.Hour, dir, []byte("test-key"))
+				mockInfisicalClient.EXPECT().ListSecrets(options).Return([]models.Secret{{SecretKey: "DB_PASSWORD"}}, nil)
+				_, _ = provider.NewLastKnownGoodInfisicalClient(mockInfisicalClient, cache, "https://app.infisical.com", "test-identity").ListSecrets(options)
+				files, _ := os.ReadDir(dir)
+				for _, file := range files {
+					storedAt := current.Add(-time.Hour - time.Second)
+					_ = os.Chtimes(filepath.Join(dir, file.Name()), storedAt, storedAt)
+				}
+
+				// When
+				_, err := provider.NewLastKnownGoodCache(time.Hour, dir, []byte("test-key"))
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if files, _ := os.ReadDir(dir); len(files) != 0 {
+					t.Errorf("unexpected files: %v", files)
+				}
+			},
+		},
+		{
+			"FailedToStoreOnDiskWithoutKey",
+			func(t *testing.T) {
+				// When
+				_, err := provider.NewLastKnownGoodCache(time.Hour, t.TempDir(), nil)
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+			},
+		},
+	} {
+		mockInfisicalClient = mock_provider.NewMockInfisicalClient(gomock.NewController(t))
+		cache, _ = provider.NewLastKnownGoodCache(time.Hour, "", nil)
+		current = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		restore := provider.SetNow(func() time.Time { return current })
+		options = provider.ListSecretsOptions{
+			ListSecretsOptions: infisical.ListSecretsOptions{
+				ProjectSlug: "test-project",
+				Environment: "dev",
+				SecretPath:  "/",
+			},
+		}
+		unavailable = &infisical.APIError{StatusCode: http.StatusServiceUnavailable}
+
+		t.Run(testcase.name, testcase.f)
+		restore()
+	}
+}
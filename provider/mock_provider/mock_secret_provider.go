@@ -10,6 +10,7 @@
 package mock_provider
 
 import (
+	context "context"
 	reflect "reflect"
 
 	provider "github.com/gidoichi/secrets-store-csi-driver-provider-infisical/provider"
@@ -43,17 +44,17 @@ func (m *MockInfisicalClientFactory) EXPECT() *MockInfisicalClientFactoryMockRec
 }
 
 // NewClient mocks base method.
-func (m *MockInfisicalClientFactory) NewClient(config infisical.Config) provider.InfisicalClient {
+func (m *MockInfisicalClientFactory) NewClient(ctx context.Context, config infisical.Config) provider.InfisicalClient {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "NewClient", config)
+	ret := m.ctrl.Call(m, "NewClient", ctx, config)
 	ret0, _ := ret[0].(provider.InfisicalClient)
 	return ret0
 }
 
 // NewClient indicates an expected call of NewClient.
-func (mr *MockInfisicalClientFactoryMockRecorder) NewClient(config any) *gomock.Call {
+func (mr *MockInfisicalClientFactoryMockRecorder) NewClient(ctx, config any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewClient", reflect.TypeOf((*MockInfisicalClientFactory)(nil).NewClient), config)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewClient", reflect.TypeOf((*MockInfisicalClientFactory)(nil).NewClient), ctx, config)
 }
 
 // MockInfisicalClient is a mock of InfisicalClient interface.
@@ -79,8 +80,83 @@ func (m *MockInfisicalClient) EXPECT() *MockInfisicalClientMockRecorder {
 	return m.recorder
 }
 
+// AwsIamAuthLogin mocks base method.
+func (m *MockInfisicalClient) AwsIamAuthLogin(arg0 string) (api.MachineIdentityAuthLoginResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AwsIamAuthLogin", arg0)
+	ret0, _ := ret[0].(api.MachineIdentityAuthLoginResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AwsIamAuthLogin indicates an expected call of AwsIamAuthLogin.
+func (mr *MockInfisicalClientMockRecorder) AwsIamAuthLogin(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AwsIamAuthLogin", reflect.TypeOf((*MockInfisicalClient)(nil).AwsIamAuthLogin), arg0)
+}
+
+// AzureAuthLogin mocks base method.
+func (m *MockInfisicalClient) AzureAuthLogin(arg0, arg1 string) (api.MachineIdentityAuthLoginResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AzureAuthLogin", arg0, arg1)
+	ret0, _ := ret[0].(api.MachineIdentityAuthLoginResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AzureAuthLogin indicates an expected call of AzureAuthLogin.
+func (mr *MockInfisicalClientMockRecorder) AzureAuthLogin(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AzureAuthLogin", reflect.TypeOf((*MockInfisicalClient)(nil).AzureAuthLogin), arg0, arg1)
+}
+
+// GcpIdTokenAuthLogin mocks base method.
+func (m *MockInfisicalClient) GcpIdTokenAuthLogin(arg0 string) (api.MachineIdentityAuthLoginResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GcpIdTokenAuthLogin", arg0)
+	ret0, _ := ret[0].(api.MachineIdentityAuthLoginResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GcpIdTokenAuthLogin indicates an expected call of GcpIdTokenAuthLogin.
+func (mr *MockInfisicalClientMockRecorder) GcpIdTokenAuthLogin(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GcpIdTokenAuthLogin", reflect.TypeOf((*MockInfisicalClient)(nil).GcpIdTokenAuthLogin), arg0)
+}
+
+// GetSecret mocks base method.
+func (m *MockInfisicalClient) GetSecret(arg0 provider.GetSecretOptions) (models.Secret, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSecret", arg0)
+	ret0, _ := ret[0].(models.Secret)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSecret indicates an expected call of GetSecret.
+func (mr *MockInfisicalClientMockRecorder) GetSecret(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSecret", reflect.TypeOf((*MockInfisicalClient)(nil).GetSecret), arg0)
+}
+
+// KubernetesAuthLogin mocks base method.
+func (m *MockInfisicalClient) KubernetesAuthLogin(arg0, arg1 string) (api.MachineIdentityAuthLoginResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "KubernetesAuthLogin", arg0, arg1)
+	ret0, _ := ret[0].(api.MachineIdentityAuthLoginResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// KubernetesAuthLogin indicates an expected call of KubernetesAuthLogin.
+func (mr *MockInfisicalClientMockRecorder) KubernetesAuthLogin(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "KubernetesAuthLogin", reflect.TypeOf((*MockInfisicalClient)(nil).KubernetesAuthLogin), arg0, arg1)
+}
+
 // ListSecrets mocks base method.
-func (m *MockInfisicalClient) ListSecrets(arg0 infisical.ListSecretsOptions) ([]models.Secret, error) {
+func (m *MockInfisicalClient) ListSecrets(arg0 provider.ListSecretsOptions) ([]models.Secret, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "ListSecrets", arg0)
 	ret0, _ := ret[0].([]models.Secret)
@@ -94,6 +170,48 @@ func (mr *MockInfisicalClientMockRecorder) ListSecrets(arg0 any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSecrets", reflect.TypeOf((*MockInfisicalClient)(nil).ListSecrets), arg0)
 }
 
+// OidcAuthLogin mocks base method.
+func (m *MockInfisicalClient) OidcAuthLogin(arg0, arg1 string) (api.MachineIdentityAuthLoginResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "OidcAuthLogin", arg0, arg1)
+	ret0, _ := ret[0].(api.MachineIdentityAuthLoginResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// OidcAuthLogin indicates an expected call of OidcAuthLogin.
+func (mr *MockInfisicalClientMockRecorder) OidcAuthLogin(arg0, arg1 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "OidcAuthLogin", reflect.TypeOf((*MockInfisicalClient)(nil).OidcAuthLogin), arg0, arg1)
+}
+
+// RenewAccessToken mocks base method.
+func (m *MockInfisicalClient) RenewAccessToken(arg0 string) (api.MachineIdentityAuthLoginResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RenewAccessToken", arg0)
+	ret0, _ := ret[0].(api.MachineIdentityAuthLoginResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RenewAccessToken indicates an expected call of RenewAccessToken.
+func (mr *MockInfisicalClientMockRecorder) RenewAccessToken(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RenewAccessToken", reflect.TypeOf((*MockInfisicalClient)(nil).RenewAccessToken), arg0)
+}
+
+// SetAccessToken mocks base method.
+func (m *MockInfisicalClient) SetAccessToken(arg0 string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "SetAccessToken", arg0)
+}
+
+// SetAccessToken indicates an expected call of SetAccessToken.
+func (mr *MockInfisicalClientMockRecorder) SetAccessToken(arg0 any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetAccessToken", reflect.TypeOf((*MockInfisicalClient)(nil).SetAccessToken), arg0)
+}
+
 // UniversalAuthLogin mocks base method.
 func (m *MockInfisicalClient) UniversalAuthLogin(arg0, arg1 string) (api.MachineIdentityAuthLoginResponse, error) {
 	m.ctrl.T.Helper()
@@ -108,3 +226,17 @@ func (mr *MockInfisicalClientMockRecorder) UniversalAuthLogin(arg0, arg1 any) *g
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UniversalAuthLogin", reflect.TypeOf((*MockInfisicalClient)(nil).UniversalAuthLogin), arg0, arg1)
 }
+
+// WithContext mocks base method.
+func (m *MockInfisicalClient) WithContext(ctx context.Context) provider.InfisicalClient {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithContext", ctx)
+	ret0, _ := ret[0].(provider.InfisicalClient)
+	return ret0
+}
+
+// WithContext indicates an expected call of WithContext.
+func (mr *MockInfisicalClientMockRecorder) WithContext(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithContext", reflect.TypeOf((*MockInfisicalClient)(nil).WithContext), ctx)
+}
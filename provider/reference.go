@@ -0,0 +1,159 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// DefaultMaxReferenceDepth is the length of chains of references followed when ListSecretsOptions.MaxReferenceDepth is zero.
+const DefaultMaxReferenceDepth = 10
+
+var (
+	// ErrReferenceCycle is returned when a secret references itself through other secrets.
+	ErrReferenceCycle = errors.New("references form a cycle")
+	// ErrUnknownReference is returned in strict mode when a referenced secret does not exist.
+	ErrUnknownReference = errors.New("referenced secret is not found")
+	// ErrReferenceDepthExceeded is returned when a chain of references is longer than the max depth.
+	ErrReferenceDepthExceeded = errors.New("references are nested too deeply")
+)
+
+// ReferenceError is returned when references in the value of a secret cannot be expanded.
+type ReferenceError struct {
+	// Key is the key of the secret whose value has the references.
+	Key string
+	// Chain is the references followed from the secret to the offending one.
+	Chain []string
+	Err   error
+}
+
+func (e *ReferenceError) Error() string {
+	return fmt.Sprintf("failed to expand references of %s: %s: %s", e.Key, strings.Join(e.Chain, " -> "), e.Err)
+}
+
+func (e *ReferenceError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	// secRefRegex matches references.
+	// c.f. https://github.com/Infisical/infisical/blob/a6f4a95821d2dd597a801af7ec873a98d46b5ff8/cli/packages/util/secrets.go#L333
+	secRefRegex = regexp.MustCompile(`\${([^\}]*)}`)
+	// escapedSecRefRegex matches references, and $${ which is written as ${ without starting a reference.
+	escapedSecRefRegex = regexp.MustCompile(`\$\$\{|\${([^\}]*)}`)
+)
+
+// referenceResolver expands references in the values of secrets in a folder.
+// ${KEY} references a secret in the folder, and ${env.folder.KEY} references a secret in another environment or folder.
+// $${ is written as a literal ${ only when references are escaped, since Infisical expands it as $ followed by a reference.
+// c.f. https://github.com/Infisical/infisical/blob/a6f4a95821d2dd597a801af7ec873a98d46b5ff8/cli/packages/util/secrets.go#L335
+type referenceResolver struct {
+	// strict fails on references to unknown secrets, which are expanded into empty strings otherwise
+	strict   bool
+	maxDepth int
+	// regex matches the references, and $${ when references are escaped
+	regex *regexp.Regexp
+	// values are the values before expansion, keyed by the references to them
+	values map[string]string
+	// expanded are the values after expansion, keyed by the references to them
+	expanded map[string]string
+	// fetch returns the value of a secret in another environment or folder, and whether it exists
+	fetch func(env string, path []string, key string) (string, bool, error)
+	// chain is the references being expanded
+	chain []string
+}
+
+func newReferenceResolver(options ListSecretsOptions, values map[string]string, fetch func(env string, path []string, key string) (string, bool, error)) *referenceResolver {
+	maxDepth := options.MaxReferenceDepth
+	if maxDepth == 0 {
+		maxDepth = DefaultMaxReferenceDepth
+	}
+
+	regex := secRefRegex
+	if options.EscapeReferences {
+		regex = escapedSecRefRegex
+	}
+
+	return &referenceResolver{
+		strict:   options.StrictReferences,
+		maxDepth: maxDepth,
+		regex:    regex,
+		values:   values,
+		expanded: map[string]string{},
+		fetch:    fetch,
+	}
+}
+
+// resolve returns the value referenced by the reference with the references in it expanded.
+func (r *referenceResolver) resolve(reference string) (string, error) {
+	if value, ok := r.expanded[reference]; ok {
+		return value, nil
+	}
+	if slices.Contains(r.chain, reference) {
+		return "", r.error(reference, ErrReferenceCycle)
+	}
+	if len(r.chain) > r.maxDepth {
+		return "", r.error(reference, fmt.Errorf("%w, max depth is %d", ErrReferenceDepthExceeded, r.maxDepth))
+	}
+
+	value, ok := r.values[reference]
+	if !ok {
+		if r.strict {
+			return "", r.error(reference, ErrUnknownReference)
+		}
+		return "", nil
+	}
+
+	r.chain = append(r.chain, reference)
+	defer func() { r.chain = r.chain[:len(r.chain)-1] }()
+	var expanded strings.Builder
+	last := 0
+	for _, match := range r.regex.FindAllStringSubmatchIndex(value, -1) {
+		expanded.WriteString(value[last:match[0]])
+		last = match[1]
+		// $${ => ${
+		if match[2] < 0 {
+			expanded.WriteString("${")
+			continue
+		}
+
+		// ${KEY} => KEY
+		ref := value[match[2]:match[3]]
+		// ${env.folder.KEY} => [env folder KEY]
+		if parts := strings.Split(ref, "."); len(parts) > 1 {
+			if _, ok := r.values[ref]; !ok {
+				refValue, found, err := r.fetch(parts[0], parts[1:len(parts)-1], parts[len(parts)-1])
+				if err != nil {
+					return "", err
+				}
+				if found {
+					r.values[ref] = refValue
+				}
+			}
+		}
+
+		refValue, err := r.resolve(ref)
+		if err != nil {
+			return "", err
+		}
+		expanded.WriteString(refValue)
+	}
+	expanded.WriteString(value[last:])
+
+	r.expanded[reference] = expanded.String()
+	return expanded.String(), nil
+}
+
+func (r *referenceResolver) error(reference string, err error) error {
+	key := reference
+	if len(r.chain) > 0 {
+		key = r.chain[0]
+	}
+	return &ReferenceError{
+		Key:   key,
+		Chain: append(slices.Clone(r.chain), reference),
+		Err:   err,
+	}
+}
@@ -0,0 +1,211 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/metrics"
+	infisical "github.com/infisical/go-sdk"
+	sdkerrors "github.com/infisical/go-sdk/packages/errors"
+)
+
+// RetryConfig limits the retries of idempotent calls to Infisical.
+type RetryConfig struct {
+	// MaxAttempts is the number of attempts of a call including the first one, which must be positive.
+	// Calls are not retried when it is 1.
+	MaxAttempts int
+	// InitialBackoff is the backoff before the first retry, which is doubled for each retry up to MaxBackoff.
+	// Backoffs are jittered between the half and the whole.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Validate reports whether the limits can be retried with.
+func (c RetryConfig) Validate() error {
+	if c.MaxAttempts < 1 {
+		return fmt.Errorf("max attempts %d must be positive", c.MaxAttempts)
+	}
+	if c.InitialBackoff <= 0 {
+		return fmt.Errorf("initial backoff %s must be positive", c.InitialBackoff)
+	}
+	if c.MaxBackoff < c.InitialBackoff {
+		return fmt.Errorf("max backoff %s must not be less than the initial backoff %s", c.MaxBackoff, c.InitialBackoff)
+	}
+	return nil
+}
+
+// DefaultRetryConfig is the RetryConfig used unless configured.
+var DefaultRetryConfig = RetryConfig{
+	MaxAttempts:    3,
+	InitialBackoff: 200 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+}
+
+// retryingInfisicalClient decorates InfisicalClient to retry calls which failed temporarily within the deadline of the context.
+// Logins and renewals are retried as well, since a failed request has not issued a token.
+// The calls of the SDK, i.e. logins, are returned when the context is done without waiting for them,
+// while the other calls are cancelled by the context.
+type retryingInfisicalClient struct {
+	InfisicalClient
+	ctx    context.Context
+	config RetryConfig
+}
+
+// NewRetryingInfisicalClient returns InfisicalClient retrying the calls of client within the deadline of ctx.
+// The client should be bound to ctx, as NewClient of InfisicalClientFactory does.
+func NewRetryingInfisicalClient(ctx context.Context, client InfisicalClient, config RetryConfig) InfisicalClient {
+	return &retryingInfisicalClient{
+		InfisicalClient: client,
+		ctx:             ctx,
+		config:          config,
+	}
+}
+
+func (c *retryingInfisicalClient) WithContext(ctx context.Context) InfisicalClient {
+	return NewRetryingInfisicalClient(ctx, c.InfisicalClient.WithContext(ctx), c.config)
+}
+
+func (c *retryingInfisicalClient) UniversalAuthLogin(clientID, clientSecret string) (infisical.MachineIdentityCredential, error) {
+	return retry(c, "UniversalAuthLogin", func() (infisical.MachineIdentityCredential, error) {
+		return callWithContext(c.ctx, func() (infisical.MachineIdentityCredential, error) {
+			return c.InfisicalClient.UniversalAuthLogin(clientID, clientSecret)
+		})
+	})
+}
+
+func (c *retryingInfisicalClient) KubernetesAuthLogin(identityID, serviceAccountToken string) (infisical.MachineIdentityCredential, error) {
+	return retry(c, "KubernetesAuthLogin", func() (infisical.MachineIdentityCredential, error) {
+		return callWithContext(c.ctx, func() (infisical.MachineIdentityCredential, error) {
+			return c.InfisicalClient.KubernetesAuthLogin(identityID, serviceAccountToken)
+		})
+	})
+}
+
+func (c *retryingInfisicalClient) OidcAuthLogin(identityID, jwt string) (infisical.MachineIdentityCredential, error) {
+	return retry(c, "OidcAuthLogin", func() (infisical.MachineIdentityCredential, error) {
+		return callWithContext(c.ctx, func() (infisical.MachineIdentityCredential, error) {
+			return c.InfisicalClient.OidcAuthLogin(identityID, jwt)
+		})
+	})
+}
+
+func (c *retryingInfisicalClient) AwsIamAuthLogin(identityID string) (infisical.MachineIdentityCredential, error) {
+	return retry(c, "AwsIamAuthLogin", func() (infisical.MachineIdentityCredential, error) {
+		return callWithContext(c.ctx, func() (infisical.MachineIdentityCredential, error) {
+			return c.InfisicalClient.AwsIamAuthLogin(identityID)
+		})
+	})
+}
+
+func (c *retryingInfisicalClient) GcpIdTokenAuthLogin(identityID string) (infisical.MachineIdentityCredential, error) {
+	return retry(c, "GcpIdTokenAuthLogin", func() (infisical.MachineIdentityCredential, error) {
+		return callWithContext(c.ctx, func() (infisical.MachineIdentityCredential, error) {
+			return c.InfisicalClient.GcpIdTokenAuthLogin(identityID)
+		})
+	})
+}
+
+func (c *retryingInfisicalClient) AzureAuthLogin(identityID, resource string) (infisical.MachineIdentityCredential, error) {
+	return retry(c, "AzureAuthLogin", func() (infisical.MachineIdentityCredential, error) {
+		return callWithContext(c.ctx, func() (infisical.MachineIdentityCredential, error) {
+			return c.InfisicalClient.AzureAuthLogin(identityID, resource)
+		})
+	})
+}
+
+func (c *retryingInfisicalClient) RenewAccessToken(accessToken string) (infisical.MachineIdentityCredential, error) {
+	return retry(c, "RenewAccessToken", func() (infisical.MachineIdentityCredential, error) {
+		return c.InfisicalClient.RenewAccessToken(accessToken)
+	})
+}
+
+func (c *retryingInfisicalClient) ListSecrets(options ListSecretsOptions) ([]infisical.Secret, error) {
+	return retry(c, "ListSecrets", func() ([]infisical.Secret, error) {
+		return c.InfisicalClient.ListSecrets(options)
+	})
+}
+
+func (c *retryingInfisicalClient) GetSecret(options GetSecretOptions) (infisical.Secret, error) {
+	return retry(c, "GetSecret", func() (infisical.Secret, error) {
+		return c.InfisicalClient.GetSecret(options)
+	})
+}
+
+// retry calls call until it succeeds, fails permanently, or runs out of the attempts or the deadline.
+// The Retry-After header of a response with 429 Too Many Requests is waited for instead of the backoff,
+// and the last error is returned without waiting when the deadline would pass before the next attempt.
+func retry[T any](c *retryingInfisicalClient, operation string, call func() (T, error)) (T, error) {
+	backoff := c.config.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		if err := c.ctx.Err(); err != nil {
+			var zero T
+			return zero, err
+		}
+		result, err := call()
+		if err == nil || attempt >= c.config.MaxAttempts || !isRetryable(err) {
+			return result, err
+		}
+
+		wait := backoff/2 + rand.N(backoff/2+1)
+		var apiErr *infisical.APIError
+		var retryAfterErr *RetryAfterError
+		if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusTooManyRequests && errors.As(err, &retryAfterErr) {
+			wait = retryAfterErr.RetryAfter
+		}
+		if deadline, ok := c.ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return result, err
+		}
+		select {
+		case <-c.ctx.Done():
+			return result, err
+		case <-time.After(wait):
+		}
+
+		metrics.APIRetries.WithLabelValues(operation).Inc()
+		backoff = min(backoff*2, c.config.MaxBackoff)
+	}
+}
+
+// isRetryable reports whether the error is temporary: the request did not complete, or the API is overloaded, unavailable,
+// or failed with an internal error, which a retry of the idempotent call may not hit.
+func isRetryable(err error) bool {
+	var apiErr *infisical.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.StatusCode {
+		case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	var requestErr *sdkerrors.RequestError
+	return errors.As(err, &requestErr)
+}
+
+// callWithContext returns the result of the call of the SDK, or the error of ctx when it is done first.
+// The call is left running in the background, since the SDK does not accept contexts.
+func callWithContext[T any](ctx context.Context, call func() (T, error)) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+
+	type result struct {
+		value T
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		value, err := call()
+		done <- result{value: value, err: err}
+	}()
+	select {
+	case r := <-done:
+		return r.value, r.err
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
@@ -0,0 +1,358 @@
+package provider_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/provider"
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/provider/mock_provider"
+	infisical "github.com/infisical/go-sdk"
+	sdkerrors "github.com/infisical/go-sdk/packages/errors"
+	"github.com/infisical/go-sdk/packages/models"
+	"go.uber.org/mock/gomock"
+)
+
+func TestRetryingInfisicalClientRetries(t *testing.T) {
+	var (
+		ctx                 context.Context
+		mockInfisicalClient *mock_provider.MockInfisicalClient
+		retryConfig         provider.RetryConfig
+	)
+
+	for _, testcase := range []struct {
+		name string
+		f    func(t *testing.T)
+	}{
+		{
+			"SuccessfullyAfterServiceUnavailable",
+			func(t *testing.T) {
+				// Given
+				gomock.InOrder(
+					mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return(nil, &infisical.APIError{StatusCode: http.StatusServiceUnavailable}),
+					mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{{SecretKey: "DB_PASSWORD"}}, nil),
+				)
+				client := provider.NewRetryingInfisicalClient(ctx, mockInfisicalClient, retryConfig)
+
+				// When
+				secrets, err := client.ListSecrets(provider.ListSecretsOptions{})
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(secrets) != 1 {
+					t.Errorf("unexpected secrets: %v", secrets)
+				}
+			},
+		},
+		{
+			"SuccessfullyAfterInternalServerError",
+			func(t *testing.T) {
+				// Given
+				gomock.InOrder(
+					mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return(nil, &infisical.APIError{StatusCode: http.StatusInternalServerError}),
+					mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{{SecretKey: "DB_PASSWORD"}}, nil),
+				)
+				client := provider.NewRetryingInfisicalClient(ctx, mockInfisicalClient, retryConfig)
+
+				// When
+				secrets, err := client.ListSecrets(provider.ListSecretsOptions{})
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(secrets) != 1 {
+					t.Errorf("unexpected secrets: %v", secrets)
+				}
+			},
+		},
+		{
+			"SuccessfullyAfterRequestError",
+			func(t *testing.T) {
+				// Given
+				gomock.InOrder(
+					mockInfisicalClient.EXPECT().GetSecret(gomock.Any()).Return(models.Secret{}, sdkerrors.NewRequestError("CallGetSecret", errors.New("connection reset by peer"))),
+					mockInfisicalClient.EXPECT().GetSecret(gomock.Any()).Return(models.Secret{SecretKey: "DB_PASSWORD"}, nil),
+				)
+				client := provider.NewRetryingInfisicalClient(ctx, mockInfisicalClient, retryConfig)
+
+				// When
+				secret, err := client.GetSecret(provider.GetSecretOptions{})
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if secret.SecretKey != "DB_PASSWORD" {
+					t.Errorf("unexpected secret: %v", secret)
+				}
+			},
+		},
+		{
+			"FailedAfterMaxAttempts",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return(nil, &infisical.APIError{StatusCode: http.StatusBadGateway}).Times(3)
+				client := provider.NewRetryingInfisicalClient(ctx, mockInfisicalClient, retryConfig)
+
+				// When
+				_, err := client.ListSecrets(provider.ListSecretsOptions{})
+
+				// Then
+				var apiErr *infisical.APIError
+				if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadGateway {
+					t.Errorf("unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			"FailedWithoutRetryingPermanentError",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return(nil, &infisical.APIError{StatusCode: http.StatusForbidden})
+				client := provider.NewRetryingInfisicalClient(ctx, mockInfisicalClient, retryConfig)
+
+				// When
+				_, err := client.ListSecrets(provider.ListSecretsOptions{})
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+			},
+		},
+		{
+			"SuccessfullyAfterRetryingLogin",
+			func(t *testing.T) {
+				// Given
+				gomock.InOrder(
+					mockInfisicalClient.EXPECT().UniversalAuthLogin("client-id", "client-secret").Return(infisical.MachineIdentityCredential{}, &infisical.APIError{StatusCode: http.StatusServiceUnavailable}),
+					mockInfisicalClient.EXPECT().UniversalAuthLogin("client-id", "client-secret").Return(infisical.MachineIdentityCredential{AccessToken: "access-token"}, nil),
+				)
+				client := provider.NewRetryingInfisicalClient(ctx, mockInfisicalClient, retryConfig)
+
+				// When
+				credential, err := client.UniversalAuthLogin("client-id", "client-secret")
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if credential.AccessToken != "access-token" {
+					t.Errorf("unexpected credential: %v", credential)
+				}
+			},
+		},
+		{
+			"SuccessfullyAfterRetryingRenewal",
+			func(t *testing.T) {
+				// Given
+				gomock.InOrder(
+					mockInfisicalClient.EXPECT().RenewAccessToken("access-token").Return(infisical.MachineIdentityCredential{}, sdkerrors.NewRequestError("CallRenewAccessToken", errors.New("connection reset by peer"))),
+					mockInfisicalClient.EXPECT().RenewAccessToken("access-token").Return(infisical.MachineIdentityCredential{AccessToken: "renewed-access-token"}, nil),
+				)
+				client := provider.NewRetryingInfisicalClient(ctx, mockInfisicalClient, retryConfig)
+
+				// When
+				credential, err := client.RenewAccessToken("access-token")
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if credential.AccessToken != "renewed-access-token" {
+					t.Errorf("unexpected credential: %v", credential)
+				}
+			},
+		},
+		{
+			"FailedWithoutRetryingRejectedLogin",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClient.EXPECT().UniversalAuthLogin("client-id", "client-secret").Return(infisical.MachineIdentityCredential{}, &infisical.APIError{StatusCode: http.StatusUnauthorized})
+				client := provider.NewRetryingInfisicalClient(ctx, mockInfisicalClient, retryConfig)
+
+				// When
+				_, err := client.UniversalAuthLogin("client-id", "client-secret")
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+			},
+		},
+		{
+			"SuccessfullyAfterRetryAfter",
+			func(t *testing.T) {
+				// Given
+				gomock.InOrder(
+					mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return(nil, &provider.RetryAfterError{
+						Err:        &infisical.APIError{StatusCode: http.StatusTooManyRequests},
+						RetryAfter: 100 * time.Millisecond,
+					}),
+					mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{}, nil),
+				)
+				client := provider.NewRetryingInfisicalClient(ctx, mockInfisicalClient, retryConfig)
+				start := time.Now()
+
+				// When
+				_, err := client.ListSecrets(provider.ListSecretsOptions{})
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+					t.Errorf("retried before Retry-After: %s", elapsed)
+				}
+			},
+		},
+		{
+			"FailedWithoutWaitingRetryAfterBeyondDeadline",
+			func(t *testing.T) {
+				// Given
+				ctx, cancel := context.WithTimeout(ctx, time.Second)
+				defer cancel()
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return(nil, &provider.RetryAfterError{
+					Err:        &infisical.APIError{StatusCode: http.StatusTooManyRequests},
+					RetryAfter: time.Minute,
+				})
+				client := provider.NewRetryingInfisicalClient(ctx, mockInfisicalClient, retryConfig)
+				start := time.Now()
+
+				// When
+				_, err := client.ListSecrets(provider.ListSecretsOptions{})
+
+				// Then
+				var apiErr *infisical.APIError
+				if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+					t.Errorf("unexpected error: %v", err)
+				}
+				if elapsed := time.Since(start); elapsed >= time.Second {
+					t.Errorf("waited beyond the deadline: %s", elapsed)
+				}
+			},
+		},
+		{
+			"FailedWithLoginExceedingDeadline",
+			func(t *testing.T) {
+				// Given
+				ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+				defer cancel()
+				mockInfisicalClient.EXPECT().UniversalAuthLogin("client-id", "client-secret").DoAndReturn(func(string, string) (infisical.MachineIdentityCredential, error) {
+					time.Sleep(time.Second)
+					return infisical.MachineIdentityCredential{}, nil
+				})
+				client := provider.NewRetryingInfisicalClient(ctx, mockInfisicalClient, retryConfig)
+				start := time.Now()
+
+				// When
+				_, err := client.UniversalAuthLogin("client-id", "client-secret")
+
+				// Then
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("unexpected error: %v", err)
+				}
+				if elapsed := time.Since(start); elapsed >= time.Second {
+					t.Errorf("waited beyond the deadline: %s", elapsed)
+				}
+			},
+		},
+	} {
+		ctx = context.Background()
+		mockInfisicalClient = mock_provider.NewMockInfisicalClient(gomock.NewController(t))
+		retryConfig = provider.RetryConfig{
+			MaxAttempts:    3,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     2 * time.Millisecond,
+		}
+
+		t.Run(testcase.name, testcase.f)
+	}
+}
+
+func TestRetryConfigValidates(t *testing.T) {
+	for _, testcase := range []struct {
+		name string
+		f    func(t *testing.T)
+	}{
+		{
+			"SuccessfullyWithDefaultRetryConfig",
+			func(t *testing.T) {
+				// When
+				err := provider.DefaultRetryConfig.Validate()
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithoutRetries",
+			func(t *testing.T) {
+				// Given
+				retryConfig := provider.RetryConfig{MaxAttempts: 1, InitialBackoff: time.Second, MaxBackoff: time.Second}
+
+				// When
+				err := retryConfig.Validate()
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"FailedWithoutAttempts",
+			func(t *testing.T) {
+				// Given
+				retryConfig := provider.RetryConfig{MaxAttempts: 0, InitialBackoff: time.Second, MaxBackoff: time.Second}
+
+				// When
+				err := retryConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+			},
+		},
+		{
+			"FailedWithNegativeInitialBackoff",
+			func(t *testing.T) {
+				// Given
+				retryConfig := provider.RetryConfig{MaxAttempts: 3, InitialBackoff: -time.Second, MaxBackoff: time.Second}
+
+				// When
+				err := retryConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+			},
+		},
+		{
+			"FailedWithMaxBackoffLessThanInitialBackoff",
+			func(t *testing.T) {
+				// Given
+				retryConfig := provider.RetryConfig{MaxAttempts: 3, InitialBackoff: time.Second, MaxBackoff: time.Millisecond}
+
+				// When
+				err := retryConfig.Validate()
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+			},
+		},
+	} {
+		t.Run(testcase.name, testcase.f)
+	}
+}
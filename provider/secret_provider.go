@@ -2,176 +2,345 @@
 package provider
 
 import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"path"
-	"regexp"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/metrics"
+	"github.com/go-resty/resty/v2"
 	infisical "github.com/infisical/go-sdk"
 )
 
 type InfisicalClientFactory interface {
-	NewClient(config infisical.Config) InfisicalClient
+	// NewClient returns InfisicalClient used within ctx.
+	NewClient(ctx context.Context, config infisical.Config) InfisicalClient
 }
 
-func NewInfisicalClientFactory() InfisicalClientFactory {
-	return &infisicalClientFactory{}
+// NewInfisicalClientFactory returns InfisicalClientFactory whose clients retry idempotent calls with retryConfig.
+func NewInfisicalClientFactory(retryConfig RetryConfig) InfisicalClientFactory {
+	return &infisicalClientFactory{
+		retryConfig: retryConfig,
+	}
 }
 
-type infisicalClientFactory struct{}
+type infisicalClientFactory struct {
+	retryConfig RetryConfig
+}
 
-func (f *infisicalClientFactory) NewClient(config infisical.Config) InfisicalClient {
-	return NewInfisicalClient(config)
+func (f *infisicalClientFactory) NewClient(ctx context.Context, config infisical.Config) InfisicalClient {
+	return NewRetryingInfisicalClient(ctx, NewInfisicalClient(config).WithContext(ctx), f.retryConfig)
 }
 
 type InfisicalClient interface {
+	// WithContext returns the client sharing the access token whose calls are bound to ctx.
+	WithContext(ctx context.Context) InfisicalClient
+	SetAccessToken(string)
 	UniversalAuthLogin(string, string) (infisical.MachineIdentityCredential, error)
-	ListSecrets(infisical.ListSecretsOptions) ([]infisical.Secret, error)
+	KubernetesAuthLogin(string, string) (infisical.MachineIdentityCredential, error)
+	OidcAuthLogin(string, string) (infisical.MachineIdentityCredential, error)
+	AwsIamAuthLogin(string) (infisical.MachineIdentityCredential, error)
+	GcpIdTokenAuthLogin(string) (infisical.MachineIdentityCredential, error)
+	AzureAuthLogin(string, string) (infisical.MachineIdentityCredential, error)
+	RenewAccessToken(string) (infisical.MachineIdentityCredential, error)
+	ListSecrets(ListSecretsOptions) ([]infisical.Secret, error)
+	GetSecret(GetSecretOptions) (infisical.Secret, error)
+}
+
+// ListSecretsOptions extends the options of the SDK with the ones the SDK does not support.
+type ListSecretsOptions struct {
+	infisical.ListSecretsOptions
+	// TagSlugs selects secrets having any of the tags, or all of them if MatchAllTags is true.
+	// All secrets are selected when it is empty.
+	TagSlugs     []string
+	MatchAllTags bool
+	// ExpandSecretReferencesByServer lets Infisical expand references, which are expanded by the provider with ExpandSecretReferences.
+	ExpandSecretReferencesByServer bool
+	// StrictReferences fails with ReferenceError on references to unknown secrets, which are expanded into empty strings otherwise.
+	StrictReferences bool
+	// MaxReferenceDepth limits the length of chains of references, or DefaultMaxReferenceDepth when it is zero.
+	MaxReferenceDepth int
+	// EscapeReferences writes $${ as a literal ${ without starting a reference when the provider expands references.
+	EscapeReferences bool
+}
+
+// GetSecretOptions selects a secret, which the SDK cannot retrieve by the version.
+// References are expanded with the same options as ListSecretsOptions.
+type GetSecretOptions struct {
+	SecretKey   string
+	ProjectSlug string
+	Environment string
+	SecretPath  string
+	// Version is the version of the secret to retrieve, or the latest version when it is zero.
+	Version int
+	// ExpandSecretReferences expands references by the provider,
+	// where references without folders are resolved with the latest secrets in the folder, including the imported ones with IncludeImports.
+	ExpandSecretReferences         bool
+	ExpandSecretReferencesByServer bool
+	IncludeImports                 bool
+	StrictReferences               bool
+	MaxReferenceDepth              int
+	EscapeReferences               bool
+}
+
+// listSecretsOptions returns the options listing the secrets of the folder to expand references in the secret.
+func (o GetSecretOptions) listSecretsOptions() ListSecretsOptions {
+	secretPath := o.SecretPath
+	if secretPath == "" {
+		secretPath = "/"
+	}
+	return ListSecretsOptions{
+		ListSecretsOptions: infisical.ListSecretsOptions{
+			ProjectSlug:    o.ProjectSlug,
+			Environment:    o.Environment,
+			SecretPath:     secretPath,
+			IncludeImports: o.IncludeImports,
+		},
+		StrictReferences:  o.StrictReferences,
+		MaxReferenceDepth: o.MaxReferenceDepth,
+		EscapeReferences:  o.EscapeReferences,
+	}
+}
+
+// listSecretsKey returns a digest identifying the secrets listed by the identity from the site with the options.
+func listSecretsKey(siteUrl, identity string, options ListSecretsOptions) (string, error) {
+	encoded, err := json.Marshal(options)
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.New()
+	for _, field := range [][]byte{[]byte(siteUrl), []byte(identity), encoded} {
+		digest.Write(field)
+		digest.Write([]byte{0})
+	}
+	return hex.EncodeToString(digest.Sum(nil)), nil
 }
 
 type infisicalClient struct {
 	client infisical.InfisicalClientInterface
-	auth   *infisical.MachineIdentityCredential
+	// httpClient calls the endpoints which the SDK does not support
+	httpClient *resty.Client
+	auth       *infisical.MachineIdentityCredential
+	// ctx cancels the requests of httpClient, while the calls of the SDK cannot be cancelled
+	ctx context.Context
 }
 
 func NewInfisicalClient(config infisical.Config) InfisicalClient {
 	return &infisicalClient{
-		client: infisical.NewInfisicalClient(config),
+		client:     infisical.NewInfisicalClient(config),
+		httpClient: newHTTPClient(config),
+		ctx:        context.Background(),
 	}
 }
 
-func (c *infisicalClient) UniversalAuthLogin(clientID, clientSecret string) (infisical.MachineIdentityCredential, error) {
-	return c.client.Auth().UniversalAuthLogin(clientID, clientSecret)
+func (c *infisicalClient) WithContext(ctx context.Context) InfisicalClient {
+	copied := *c
+	copied.ctx = ctx
+	return &copied
 }
 
-func (c *infisicalClient) ListSecrets(options infisical.ListSecretsOptions) ([]infisical.Secret, error) {
-	secrets, err := c.client.Secrets().List(options)
-	if err != nil {
-		return nil, err
+func (c *infisicalClient) SetAccessToken(accessToken string) {
+	c.client.Auth().SetAccessToken(accessToken)
+	c.httpClient.SetAuthToken(accessToken)
+}
+
+// loggedIn passes the access token obtained by the SDK to httpClient.
+func (c *infisicalClient) loggedIn(credential infisical.MachineIdentityCredential, err error) (infisical.MachineIdentityCredential, error) {
+	if err == nil {
+		c.httpClient.SetAuthToken(credential.AccessToken)
 	}
-	if !options.ExpandSecretReferences {
-		return secrets, nil
+	return credential, err
+}
+
+func (c *infisicalClient) UniversalAuthLogin(clientID, clientSecret string) (credential infisical.MachineIdentityCredential, err error) {
+	defer func(start time.Time) { metrics.ObserveAPIRequest("UniversalAuthLogin", start, err) }(time.Now())
+	return c.loggedIn(c.client.Auth().UniversalAuthLogin(clientID, clientSecret))
+}
+
+func (c *infisicalClient) KubernetesAuthLogin(identityID, serviceAccountToken string) (credential infisical.MachineIdentityCredential, err error) {
+	defer func(start time.Time) { metrics.ObserveAPIRequest("KubernetesAuthLogin", start, err) }(time.Now())
+	return c.loggedIn(c.client.Auth().KubernetesRawServiceAccountTokenLogin(identityID, serviceAccountToken))
+}
+
+func (c *infisicalClient) OidcAuthLogin(identityID, jwt string) (credential infisical.MachineIdentityCredential, err error) {
+	defer func(start time.Time) { metrics.ObserveAPIRequest("OidcAuthLogin", start, err) }(time.Now())
+	return c.loggedIn(c.client.Auth().OidcAuthLogin(identityID, jwt))
+}
+
+func (c *infisicalClient) AwsIamAuthLogin(identityID string) (credential infisical.MachineIdentityCredential, err error) {
+	defer func(start time.Time) { metrics.ObserveAPIRequest("AwsIamAuthLogin", start, err) }(time.Now())
+	return c.loggedIn(c.client.Auth().AwsIamAuthLogin(identityID))
+}
+
+func (c *infisicalClient) GcpIdTokenAuthLogin(identityID string) (credential infisical.MachineIdentityCredential, err error) {
+	defer func(start time.Time) { metrics.ObserveAPIRequest("GcpIdTokenAuthLogin", start, err) }(time.Now())
+	return c.loggedIn(c.client.Auth().GcpIdTokenAuthLogin(identityID))
+}
+
+func (c *infisicalClient) AzureAuthLogin(identityID, resource string) (credential infisical.MachineIdentityCredential, err error) {
+	defer func(start time.Time) { metrics.ObserveAPIRequest("AzureAuthLogin", start, err) }(time.Now())
+	return c.loggedIn(c.client.Auth().AzureAuthLogin(identityID, resource))
+}
+
+func (c *infisicalClient) RenewAccessToken(accessToken string) (credential infisical.MachineIdentityCredential, err error) {
+	defer func(start time.Time) { metrics.ObserveAPIRequest("RenewAccessToken", start, err) }(time.Now())
+	credential, err = callRenewAccessToken(c.ctx, c.httpClient, accessToken)
+	if err != nil {
+		return infisical.MachineIdentityCredential{}, err
 	}
 
-	return c.expandSecrets(secrets, options)
+	c.SetAccessToken(credential.AccessToken)
+	return credential, nil
 }
 
-func (c *infisicalClient) GetAllEnvironmentVariables(options infisical.ListSecretsOptions) ([]infisical.Secret, error) {
-	options.ExpandSecretReferences = false
-	return c.ListSecrets(options)
+// ListSecrets lists secrets in the folder, and the secrets imported into it with IncludeImports.
+// A secret in the folder takes precedence over imported secrets with the same key,
+// and an imported secret takes precedence over the ones with the same key in later imports in the order the API returns them, as in the SDK.
+func (c *infisicalClient) ListSecrets(options ListSecretsOptions) (secrets []infisical.Secret, err error) {
+	defer func(start time.Time) { metrics.ObserveAPIRequest("ListSecrets", start, err) }(time.Now())
+	return c.listSecrets(options)
 }
 
-// c.f. https://github.com/Infisical/infisical/blob/a6f4a95821d2dd597a801af7ec873a98d46b5ff8/cli/packages/util/secrets.go#L333
-var secRefRegex = regexp.MustCompile(`\${([^\}]*)}`)
+func (c *infisicalClient) GetSecret(options GetSecretOptions) (secret infisical.Secret, err error) {
+	defer func(start time.Time) { metrics.ObserveAPIRequest("GetSecret", start, err) }(time.Now())
+	return c.getSecret(options)
+}
 
-// c.f. https://github.com/Infisical/infisical/blob/a6f4a95821d2dd597a801af7ec873a98d46b5ff8/cli/packages/util/secrets.go#L335
-func (c *infisicalClient) recursivelyExpandSecret(expandedSecs map[string]string, interpolatedSecs map[string]string, crossSecRefFetch func(env string, path []string, key string) (string, error), key string) (string, error) {
-	if v, ok := expandedSecs[key]; ok {
-		return v, nil
+// getSecret retrieves the secret, and expands references in it as listSecrets does with ExpandSecretReferences,
+// so that secrets pinned to versions are expanded in the same way as the listed ones.
+func (c *infisicalClient) getSecret(options GetSecretOptions) (infisical.Secret, error) {
+	secret, err := callGetSecret(c.ctx, c.httpClient, options)
+	if err != nil || !options.ExpandSecretReferences || !strings.Contains(secret.SecretValue, "${") {
+		return secret, err
 	}
 
-	interpolatedVal, ok := interpolatedSecs[key]
-	if !ok {
-		return "", fmt.Errorf("could not find refered secret -  %s", key)
+	listOptions := options.listSecretsOptions()
+	folderSecrets, err := c.GetAllEnvironmentVariables(listOptions.ListSecretsOptions)
+	if err != nil {
+		return infisical.Secret{}, err
+	}
+	values := make(map[string]string, len(folderSecrets)+1)
+	for _, sec := range folderSecrets {
+		values[sec.SecretKey] = sec.SecretValue
+	}
+	values[secret.SecretKey] = secret.SecretValue
+	if secret.SecretValue, err = c.newReferenceResolver(listOptions, values).resolve(secret.SecretKey); err != nil {
+		return infisical.Secret{}, err
 	}
+	return secret, nil
+}
 
-	refs := secRefRegex.FindAllStringSubmatch(interpolatedVal, -1)
-	for _, val := range refs {
-		// key: "${something}" val: [${something},something]
-		interpolatedExp, interpolationKey := val[0], val[1]
-		ref := strings.Split(interpolationKey, ".")
+// listSecrets lists secrets without the SDK to keep the folder of each secret in SecretPath, the tags of secrets,
+// and the Retry-After header of error responses.
+// Imported secrets are placed in the folder of options.SecretPath.
+// Secrets are filtered by the tags after references are expanded, so that secrets without the tags can be referenced.
+func (c *infisicalClient) listSecrets(options ListSecretsOptions) ([]infisical.Secret, error) {
+	res, err := callListSecrets(c.ctx, c.httpClient, options.apiOptions())
+	if err != nil {
+		return nil, err
+	}
 
-		// ${KEY1} => [key1]
-		if len(ref) == 1 {
-			val, err := c.recursivelyExpandSecret(expandedSecs, interpolatedSecs, crossSecRefFetch, interpolationKey)
-			if err != nil {
-				return "", err
+	listed := res.Secrets
+	if options.IncludeImports {
+		rootSecrets := map[string]bool{}
+		for _, secret := range listed {
+			if secret.SecretPath == "" || path.Clean(secret.SecretPath) == path.Clean(options.SecretPath) {
+				rootSecrets[secret.SecretKey] = true
+			}
+		}
+		for _, importBlock := range res.Imports {
+			for _, importSecret := range importBlock.Secrets {
+				if !rootSecrets[importSecret.SecretKey] {
+					importSecret.SecretPath = options.SecretPath
+					listed = append(listed, importSecret)
+					rootSecrets[importSecret.SecretKey] = true
+				}
 			}
-			interpolatedVal = strings.ReplaceAll(interpolatedVal, interpolatedExp, val)
-			continue
 		}
+	}
 
-		// cross board reference ${env.folder.key1} => [env folder key1]
-		if len(ref) > 1 {
-			var err error
-			secEnv, tmpSecPath, secKey := ref[0], ref[1:len(ref)-1], ref[len(ref)-1]
-			interpolatedSecs[interpolationKey], err = crossSecRefFetch(secEnv, tmpSecPath, secKey) // get the reference value
-			if err != nil {
-				return "", err
+	var folders []string
+	secretsByFolder := map[string][]infisical.Secret{}
+	selected := map[string]bool{}
+	for _, secret := range listed {
+		if secret.SecretPath == "" {
+			secret.SecretPath = options.SecretPath
+		}
+		if _, ok := secretsByFolder[secret.SecretPath]; !ok {
+			folders = append(folders, secret.SecretPath)
+		}
+		secretsByFolder[secret.SecretPath] = append(secretsByFolder[secret.SecretPath], secret.Secret)
+		selected[path.Join(secret.SecretPath, secret.SecretKey)] = options.selects(secret)
+	}
+
+	var secrets []infisical.Secret
+	for _, folder := range folders {
+		folderSecrets := secretsByFolder[folder]
+		if options.ExpandSecretReferences {
+			// references without folders are resolved within the folder of each secret
+			folderOptions := options
+			folderOptions.SecretPath = folder
+			folderOptions.Recursive = false
+			if folderSecrets, err = c.expandSecrets(folderSecrets, folderOptions); err != nil {
+				return nil, err
 			}
-			val, err := c.recursivelyExpandSecret(expandedSecs, interpolatedSecs, crossSecRefFetch, interpolationKey)
-			if err != nil {
-				return "", err
+		}
+		for _, secret := range folderSecrets {
+			if selected[path.Join(secret.SecretPath, secret.SecretKey)] {
+				secrets = append(secrets, secret)
 			}
-			interpolatedVal = strings.ReplaceAll(interpolatedVal, interpolatedExp, val)
 		}
-
 	}
-	expandedSecs[key] = interpolatedVal
-	return interpolatedVal, nil
+	// secrets are sorted by keys as in the SDK, so that the order does not change with the order the API returns them
+	slices.SortStableFunc(secrets, func(a, b infisical.Secret) int {
+		return cmp.Or(cmp.Compare(a.SecretKey, b.SecretKey), cmp.Compare(a.SecretPath, b.SecretPath))
+	})
+	return secrets, nil
 }
 
-// c.f. https://github.com/Infisical/infisical/blob/a6f4a95821d2dd597a801af7ec873a98d46b5ff8/cli/packages/util/secrets.go#L371
-func getSecretsByKeys(secrets []infisical.Secret) map[string]infisical.Secret {
-	secretMapByName := make(map[string]infisical.Secret, len(secrets))
+// apiOptions returns the options passed to the API, which expands references only when the provider does not.
+func (o ListSecretsOptions) apiOptions() infisical.ListSecretsOptions {
+	options := o.ListSecretsOptions
+	options.ExpandSecretReferences = o.ExpandSecretReferencesByServer
+	return options
+}
 
-	for _, secret := range secrets {
-		secretMapByName[secret.SecretKey] = secret
+func (o ListSecretsOptions) selects(secret secret) bool {
+	if len(o.TagSlugs) == 0 {
+		return true
 	}
+	for _, slug := range o.TagSlugs {
+		if o.MatchAllTags && !secret.hasTag(slug) {
+			return false
+		}
+		if !o.MatchAllTags && secret.hasTag(slug) {
+			return true
+		}
+	}
+	return o.MatchAllTags
+}
 
-	return secretMapByName
+func (c *infisicalClient) GetAllEnvironmentVariables(options infisical.ListSecretsOptions) ([]infisical.Secret, error) {
+	options.ExpandSecretReferences = false
+	return c.ListSecrets(ListSecretsOptions{ListSecretsOptions: options})
 }
 
 // c.f. https://github.com/Infisical/infisical/blob/a6f4a95821d2dd597a801af7ec873a98d46b5ff8/cli/packages/util/secrets.go#L381
-func (c *infisicalClient) expandSecrets(secrets []infisical.Secret, options infisical.ListSecretsOptions) ([]infisical.Secret, error) {
-	expandedSecs := make(map[string]string)
-	interpolatedSecs := make(map[string]string)
-	// map[env.secret-path][keyname]Secret
-	crossEnvRefSecs := make(map[string]map[string]infisical.Secret) // a cache to hold all cross board reference secrets
-
+func (c *infisicalClient) expandSecrets(secrets []infisical.Secret, options ListSecretsOptions) ([]infisical.Secret, error) {
+	values := make(map[string]string, len(secrets))
 	for _, sec := range secrets {
-		// get all references in a secret
-		refs := secRefRegex.FindAllStringSubmatch(sec.SecretValue, -1)
-		// nil means its a secret without reference
-		if refs == nil {
-			expandedSecs[sec.SecretKey] = sec.SecretValue // atomic secrets without any interpolation
-		} else {
-			interpolatedSecs[sec.SecretKey] = sec.SecretValue
-		}
+		values[sec.SecretKey] = sec.SecretValue
 	}
+	resolver := c.newReferenceResolver(options, values)
 
 	for i, sec := range secrets {
-		// already present pick that up
-		if expandedVal, ok := expandedSecs[sec.SecretKey]; ok {
-			secrets[i].SecretValue = expandedVal
-			continue
-		}
-
-		expandedVal, err := c.recursivelyExpandSecret(expandedSecs, interpolatedSecs, func(env string, secPaths []string, secKey string) (string, error) {
-			secPaths = append([]string{"/"}, secPaths...)
-			secPath := path.Join(secPaths...)
-
-			secPathDot := strings.Join(secPaths, ".")
-			uniqKey := fmt.Sprintf("%s.%s", env, secPathDot)
-
-			if crossRefSec, ok := crossEnvRefSecs[uniqKey]; !ok {
-				// if not in cross reference cache, fetch it from server
-				options := options
-				options.Environment = env
-				options.SecretPath = secPath
-				refSecs, err := c.GetAllEnvironmentVariables(options)
-				if err != nil {
-					return "", fmt.Errorf("Could not fetch secrets in environment: %s secret-path: %s: %w", env, secPath, err)
-				}
-				refSecsByKey := getSecretsByKeys(refSecs)
-				// save it to avoid calling api again for same environment and folder path
-				crossEnvRefSecs[uniqKey] = refSecsByKey
-				return refSecsByKey[secKey].SecretValue, nil
-
-			} else {
-				return crossRefSec[secKey].SecretValue, nil
-			}
-		}, sec.SecretKey)
+		expandedVal, err := resolver.resolve(sec.SecretKey)
 		if err != nil {
 			return nil, err
 		}
@@ -180,3 +349,38 @@ func (c *infisicalClient) expandSecrets(secrets []infisical.Secret, options infi
 	}
 	return secrets, nil
 }
+
+// newReferenceResolver returns referenceResolver expanding references in the values of secrets in the folder of options,
+// which fetches the secrets in other environments and folders from Infisical.
+func (c *infisicalClient) newReferenceResolver(options ListSecretsOptions, values map[string]string) *referenceResolver {
+	// map[env.secret-path][keyname]Secret
+	crossEnvRefSecs := make(map[string]map[string]string) // a cache to hold all cross board reference secrets
+
+	return newReferenceResolver(options, values, func(env string, secPaths []string, secKey string) (string, bool, error) {
+		secPaths = append([]string{"/"}, secPaths...)
+		secPath := path.Join(secPaths...)
+
+		secPathDot := strings.Join(secPaths, ".")
+		uniqKey := fmt.Sprintf("%s.%s", env, secPathDot)
+
+		crossRefSec, ok := crossEnvRefSecs[uniqKey]
+		if !ok {
+			// if not in cross reference cache, fetch it from server
+			options := options.ListSecretsOptions
+			options.Environment = env
+			options.SecretPath = secPath
+			refSecs, err := c.GetAllEnvironmentVariables(options)
+			if err != nil {
+				return "", false, fmt.Errorf("Could not fetch secrets in environment: %s secret-path: %s: %w", env, secPath, err)
+			}
+			crossRefSec = make(map[string]string, len(refSecs))
+			for _, refSec := range refSecs {
+				crossRefSec[refSec.SecretKey] = refSec.SecretValue
+			}
+			// save it to avoid calling api again for same environment and folder path
+			crossEnvRefSecs[uniqKey] = crossRefSec
+		}
+		value, ok := crossRefSec[secKey]
+		return value, ok, nil
+	})
+}
@@ -0,0 +1,868 @@
+package provider_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/provider"
+	infisical "github.com/infisical/go-sdk"
+)
+
+func TestInfisicalClientListsSecrets(t *testing.T) {
+	var (
+		api    *httptest.Server
+		client provider.InfisicalClient
+	)
+
+	secretKeys := func(secrets []infisical.Secret) []string {
+		var keys []string
+		for _, secret := range secrets {
+			keys = append(keys, secret.SecretPath+":"+secret.SecretKey+"="+secret.SecretValue)
+		}
+		return keys
+	}
+	equal := func(actual, expected []string) bool {
+		if len(actual) != len(expected) {
+			return false
+		}
+		for i := range actual {
+			if actual[i] != expected[i] {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, testcase := range []struct {
+		name string
+		f    func(t *testing.T)
+	}{
+		{
+			"SuccessfullyWithAnyTags",
+			func(t *testing.T) {
+				// Given
+				options := provider.ListSecretsOptions{
+					ListSecretsOptions: infisical.ListSecretsOptions{ProjectSlug: "test-project", Environment: "dev", SecretPath: "/"},
+					TagSlugs:           []string{"payments-api", "orders-api"},
+				}
+
+				// When
+				secrets, err := client.ListSecrets(options)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if keys := secretKeys(secrets); !equal(keys, []string{"/:ORDERS=orders", "/:PAYMENTS=payments", "/:SHARED=shared-${UNTAGGED}"}) {
+					t.Errorf("unexpected secrets: %v", keys)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithAllTags",
+			func(t *testing.T) {
+				// Given
+				options := provider.ListSecretsOptions{
+					ListSecretsOptions: infisical.ListSecretsOptions{ProjectSlug: "test-project", Environment: "dev", SecretPath: "/"},
+					TagSlugs:           []string{"payments-api", "orders-api"},
+					MatchAllTags:       true,
+				}
+
+				// When
+				secrets, err := client.ListSecrets(options)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if keys := secretKeys(secrets); !equal(keys, []string{"/:SHARED=shared-${UNTAGGED}"}) {
+					t.Errorf("unexpected secrets: %v", keys)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithReferenceToSecretWithoutTags",
+			func(t *testing.T) {
+				// Given
+				options := provider.ListSecretsOptions{
+					ListSecretsOptions: infisical.ListSecretsOptions{ProjectSlug: "test-project", Environment: "dev", SecretPath: "/", ExpandSecretReferences: true},
+					TagSlugs:           []string{"payments-api"},
+					MatchAllTags:       true,
+				}
+
+				// When
+				secrets, err := client.ListSecrets(options)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if keys := secretKeys(secrets); !equal(keys, []string{"/:PAYMENTS=payments", "/:SHARED=shared-untagged"}) {
+					t.Errorf("unexpected secrets: %v", keys)
+				}
+			},
+		},
+	} {
+		api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/v3/secrets/raw" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			shared := "shared-${UNTAGGED}"
+			if r.URL.Query().Get("expandSecretReferences") == "true" {
+				// references are expanded by Infisical
+				shared = "shared-untagged"
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"secrets": []map[string]any{
+					{"secretKey": "PAYMENTS", "secretValue": "payments", "tags": []map[string]string{{"slug": "payments-api"}}},
+					{"secretKey": "SHARED", "secretValue": shared, "tags": []map[string]string{{"slug": "payments-api"}, {"slug": "orders-api"}}},
+					{"secretKey": "ORDERS", "secretValue": "orders", "tags": []map[string]string{{"slug": "orders-api"}}},
+					{"secretKey": "UNTAGGED", "secretValue": "untagged"},
+				},
+				"imports": []any{},
+			})
+		}))
+		client = provider.NewInfisicalClient(infisical.Config{SiteUrl: api.URL})
+
+		t.Run(testcase.name, testcase.f)
+
+		api.Close()
+	}
+}
+
+func TestInfisicalClientListsImportedSecrets(t *testing.T) {
+	var (
+		api    *httptest.Server
+		client provider.InfisicalClient
+	)
+
+	secretValues := func(secrets []infisical.Secret) map[string]string {
+		values := map[string]string{}
+		for _, secret := range secrets {
+			if _, ok := values[secret.SecretKey]; ok {
+				t.Errorf("duplicated secret: %s", secret.SecretKey)
+			}
+			values[secret.SecretKey] = secret.SecretValue
+		}
+		return values
+	}
+
+	for _, testcase := range []struct {
+		name string
+		f    func(t *testing.T)
+	}{
+		{
+			"SuccessfullyWithSecretsInFolderPrioritized",
+			func(t *testing.T) {
+				// Given
+				options := provider.ListSecretsOptions{
+					ListSecretsOptions: infisical.ListSecretsOptions{ProjectSlug: "test-project", Environment: "dev", SecretPath: "/", IncludeImports: true},
+				}
+
+				// When
+				secrets, err := client.ListSecrets(options)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if values := secretValues(secrets); len(values) != 4 || values["API_KEY"] != "folder" || values["DB_HOST"] != "folder" || values["DB_PORT"] != "first-import" || values["LOG_LEVEL"] != "second-import" {
+					t.Errorf("unexpected secrets: %v", values)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithSecretsInFolderPrioritizedWhenRecursive",
+			func(t *testing.T) {
+				// Given
+				options := provider.ListSecretsOptions{
+					ListSecretsOptions: infisical.ListSecretsOptions{ProjectSlug: "test-project", Environment: "dev", SecretPath: "/", IncludeImports: true, Recursive: true},
+				}
+
+				// When
+				secrets, err := client.ListSecrets(options)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if values := secretValues(secrets); len(values) != 4 || values["API_KEY"] != "folder" || values["DB_HOST"] != "folder" || values["DB_PORT"] != "first-import" || values["LOG_LEVEL"] != "second-import" {
+					t.Errorf("unexpected secrets: %v", values)
+				}
+			},
+		},
+		{
+			"SuccessfullySortedByKeys",
+			func(t *testing.T) {
+				// Given
+				options := provider.ListSecretsOptions{
+					ListSecretsOptions: infisical.ListSecretsOptions{ProjectSlug: "test-project", Environment: "dev", SecretPath: "/", IncludeImports: true},
+				}
+
+				// When
+				secrets, err := client.ListSecrets(options)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				var keys []string
+				for _, secret := range secrets {
+					keys = append(keys, secret.SecretKey)
+				}
+				if strings.Join(keys, ",") != "API_KEY,DB_HOST,DB_PORT,LOG_LEVEL" {
+					t.Errorf("unexpected order of secrets: %v", keys)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithoutImports",
+			func(t *testing.T) {
+				// Given
+				options := provider.ListSecretsOptions{
+					ListSecretsOptions: infisical.ListSecretsOptions{ProjectSlug: "test-project", Environment: "dev", SecretPath: "/"},
+				}
+
+				// When
+				secrets, err := client.ListSecrets(options)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if values := secretValues(secrets); len(values) != 2 || values["API_KEY"] != "folder" || values["DB_HOST"] != "folder" {
+					t.Errorf("unexpected secrets: %v", values)
+				}
+			},
+		},
+	} {
+		api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/v3/secrets/raw" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			imports := []any{}
+			if r.URL.Query().Get("include_imports") == "true" {
+				imports = []any{
+					map[string]any{
+						"secretPath":  "/",
+						"environment": "shared",
+						"secrets": []map[string]any{
+							{"secretKey": "DB_HOST", "secretValue": "first-import"},
+							{"secretKey": "DB_PORT", "secretValue": "first-import"},
+						},
+					},
+					map[string]any{
+						"secretPath":  "/",
+						"environment": "base",
+						"secrets": []map[string]any{
+							{"secretKey": "DB_PORT", "secretValue": "second-import"},
+							{"secretKey": "LOG_LEVEL", "secretValue": "second-import"},
+						},
+					},
+				}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"secrets": []map[string]any{
+					{"secretKey": "DB_HOST", "secretValue": "folder", "secretPath": "/"},
+					{"secretKey": "API_KEY", "secretValue": "folder", "secretPath": "/"},
+				},
+				"imports": imports,
+			})
+		}))
+		client = provider.NewInfisicalClient(infisical.Config{SiteUrl: api.URL})
+
+		t.Run(testcase.name, testcase.f)
+
+		api.Close()
+	}
+}
+
+func TestInfisicalClientExpandsReferences(t *testing.T) {
+	var (
+		api          *httptest.Server
+		client       provider.InfisicalClient
+		environments map[string]map[string]string
+		query        url.Values
+	)
+
+	options := func(strict bool, maxDepth int) provider.ListSecretsOptions {
+		return provider.ListSecretsOptions{
+			ListSecretsOptions: infisical.ListSecretsOptions{ProjectSlug: "test-project", Environment: "dev", SecretPath: "/", ExpandSecretReferences: true},
+			StrictReferences:   strict,
+			MaxReferenceDepth:  maxDepth,
+		}
+	}
+	secretValues := func(secrets []infisical.Secret) map[string]string {
+		values := map[string]string{}
+		for _, secret := range secrets {
+			values[secret.SecretKey] = secret.SecretValue
+		}
+		return values
+	}
+
+	for _, testcase := range []struct {
+		name string
+		f    func(t *testing.T)
+	}{
+		{
+			"SuccessfullyWithReferenceToOtherEnvironment",
+			func(t *testing.T) {
+				// Given
+				environments["dev"] = map[string]string{"DB_URL": "postgres://${USER}:${prod.db.PASSWORD}@db", "USER": "admin"}
+				environments["prod"] = map[string]string{"PASSWORD": "password"}
+
+				// When
+				secrets, err := client.ListSecrets(options(true, 0))
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if values := secretValues(secrets); values["DB_URL"] != "postgres://admin:password@db" {
+					t.Errorf("unexpected secrets: %v", values)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithEscapedReferences",
+			func(t *testing.T) {
+				// Given
+				environments["dev"] = map[string]string{"SCRIPT": "echo $${HOME} ${GREETING}", "GREETING": "$${USER} ${NAME}", "NAME": "infisical"}
+				options := options(true, 0)
+				options.EscapeReferences = true
+
+				// When
+				secrets, err := client.ListSecrets(options)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if values := secretValues(secrets); values["SCRIPT"] != "echo ${HOME} ${USER} infisical" || values["GREETING"] != "${USER} infisical" {
+					t.Errorf("unexpected secrets: %v", values)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithoutEscapingReferencesByDefault",
+			func(t *testing.T) {
+				// Given
+				environments["dev"] = map[string]string{"PRICE": "$${AMOUNT}", "AMOUNT": "10"}
+
+				// When
+				secrets, err := client.ListSecrets(options(true, 0))
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if values := secretValues(secrets); values["PRICE"] != "$10" {
+					t.Errorf("unexpected secrets: %v", values)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithReferencesExpandedByServer",
+			func(t *testing.T) {
+				// Given
+				environments["dev"] = map[string]string{"A": "${A}"}
+				options := options(true, 0)
+				options.ExpandSecretReferences = false
+				options.ExpandSecretReferencesByServer = true
+
+				// When
+				secrets, err := client.ListSecrets(options)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if values := secretValues(secrets); values["A"] != "${A}" {
+					t.Errorf("unexpected secrets: %v", values)
+				}
+				if query.Get("expandSecretReferences") != "true" {
+					t.Errorf("unexpected query: %v", query)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithoutExpansion",
+			func(t *testing.T) {
+				// Given
+				environments["dev"] = map[string]string{"A": "$${A} ${A}"}
+				options := options(true, 0)
+				options.ExpandSecretReferences = false
+
+				// When
+				secrets, err := client.ListSecrets(options)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if values := secretValues(secrets); values["A"] != "$${A} ${A}" {
+					t.Errorf("unexpected secrets: %v", values)
+				}
+				if query.Get("expandSecretReferences") != "false" {
+					t.Errorf("unexpected query: %v", query)
+				}
+			},
+		},
+		{
+			"FailedWithReferenceCycle",
+			func(t *testing.T) {
+				// Given
+				environments["dev"] = map[string]string{"A": "${B}", "B": "${C}", "C": "${A}"}
+
+				// When
+				_, err := client.ListSecrets(options(true, 0))
+
+				// Then
+				var referenceErr *provider.ReferenceError
+				if !errors.As(err, &referenceErr) || !errors.Is(err, provider.ErrReferenceCycle) {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if referenceErr.Key != "A" || strings.Join(referenceErr.Chain, " -> ") != "A -> B -> C -> A" {
+					t.Errorf("unexpected error: %s", referenceErr)
+				}
+			},
+		},
+		{
+			"FailedWithSelfReference",
+			func(t *testing.T) {
+				// Given
+				environments["dev"] = map[string]string{"A": "a${A}"}
+
+				// When
+				_, err := client.ListSecrets(options(true, 0))
+
+				// Then
+				var referenceErr *provider.ReferenceError
+				if !errors.As(err, &referenceErr) || !errors.Is(err, provider.ErrReferenceCycle) {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if referenceErr.Key != "A" || strings.Join(referenceErr.Chain, " -> ") != "A -> A" {
+					t.Errorf("unexpected error: %s", referenceErr)
+				}
+			},
+		},
+		{
+			"FailedWithUnknownReferenceToOtherEnvironment",
+			func(t *testing.T) {
+				// Given
+				environments["dev"] = map[string]string{"DB_PASSWORD": "${prod.db.PASSWORD}"}
+				environments["prod"] = map[string]string{}
+
+				// When
+				_, err := client.ListSecrets(options(true, 0))
+
+				// Then
+				var referenceErr *provider.ReferenceError
+				if !errors.As(err, &referenceErr) || !errors.Is(err, provider.ErrUnknownReference) {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if referenceErr.Key != "DB_PASSWORD" || strings.Join(referenceErr.Chain, " -> ") != "DB_PASSWORD -> prod.db.PASSWORD" {
+					t.Errorf("unexpected error: %s", referenceErr)
+				}
+			},
+		},
+		{
+			"FailedWithUnknownReference",
+			func(t *testing.T) {
+				// Given
+				environments["dev"] = map[string]string{"DB_URL": "${DB_HOST}"}
+
+				// When
+				_, err := client.ListSecrets(options(true, 0))
+
+				// Then
+				if !errors.Is(err, provider.ErrUnknownReference) {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithUnknownReferencesExpandedIntoEmptyStringsWhenNotStrict",
+			func(t *testing.T) {
+				// Given
+				environments["dev"] = map[string]string{"DB_URL": "postgres://${DB_USER}:${prod.db.PASSWORD}@db"}
+				environments["prod"] = map[string]string{}
+
+				// When
+				secrets, err := client.ListSecrets(options(false, 0))
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if values := secretValues(secrets); values["DB_URL"] != "postgres://:@db" {
+					t.Errorf("unexpected secrets: %v", values)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithReferencesAsDeepAsMaxDepth",
+			func(t *testing.T) {
+				// Given
+				environments["dev"] = map[string]string{"A": "${B}", "B": "${C}", "C": "${D}", "D": "d"}
+
+				// When
+				secrets, err := client.ListSecrets(options(true, 3))
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if values := secretValues(secrets); values["A"] != "d" {
+					t.Errorf("unexpected secrets: %v", values)
+				}
+			},
+		},
+		{
+			"FailedWithReferencesDeeperThanMaxDepth",
+			func(t *testing.T) {
+				// Given
+				environments["dev"] = map[string]string{"A": "${B}", "B": "${C}", "C": "${D}", "D": "d"}
+
+				// When
+				_, err := client.ListSecrets(options(true, 2))
+
+				// Then
+				var referenceErr *provider.ReferenceError
+				if !errors.As(err, &referenceErr) || !errors.Is(err, provider.ErrReferenceDepthExceeded) {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if referenceErr.Key != "A" || strings.Join(referenceErr.Chain, " -> ") != "A -> B -> C -> D" {
+					t.Errorf("unexpected error: %s", referenceErr)
+				}
+			},
+		},
+	} {
+		environments = map[string]map[string]string{}
+		api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/v3/secrets/raw" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			query = r.URL.Query()
+			environment := environments[query.Get("environment")]
+			keys := make([]string, 0, len(environment))
+			for key := range environment {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			secrets := []map[string]any{}
+			for _, key := range keys {
+				secrets = append(secrets, map[string]any{"secretKey": key, "secretValue": environment[key]})
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"secrets": secrets,
+				"imports": []any{},
+			})
+		}))
+		client = provider.NewInfisicalClient(infisical.Config{SiteUrl: api.URL})
+
+		t.Run(testcase.name, testcase.f)
+
+		api.Close()
+	}
+}
+
+func TestInfisicalClientGetsSecret(t *testing.T) {
+	var (
+		api    *httptest.Server
+		client provider.InfisicalClient
+		query  url.Values
+	)
+
+	for _, testcase := range []struct {
+		name string
+		f    func(t *testing.T)
+	}{
+		{
+			"SuccessfullyWithVersion",
+			func(t *testing.T) {
+				// Given
+				options := provider.GetSecretOptions{
+					SecretKey:   "DB_PASSWORD",
+					ProjectSlug: "test-project",
+					Environment: "dev",
+					SecretPath:  "/db",
+					Version:     12,
+				}
+
+				// When
+				secret, err := client.GetSecret(options)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if secret.SecretKey != "DB_PASSWORD" || secret.Version != 12 || secret.SecretValue != "password" {
+					t.Errorf("unexpected secret: %v", secret)
+				}
+				if query.Get("workspaceSlug") != "test-project" || query.Get("environment") != "dev" || query.Get("secretPath") != "/db" || query.Get("version") != "12" {
+					t.Errorf("unexpected query: %v", query)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithLatestVersion",
+			func(t *testing.T) {
+				// Given
+				options := provider.GetSecretOptions{
+					SecretKey:   "DB_PASSWORD",
+					ProjectSlug: "test-project",
+					Environment: "dev",
+				}
+
+				// When
+				_, err := client.GetSecret(options)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if query.Has("version") || query.Get("secretPath") != "/" {
+					t.Errorf("unexpected query: %v", query)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithReferencesExpandedByProvider",
+			func(t *testing.T) {
+				// Given
+				options := provider.GetSecretOptions{
+					SecretKey:              "DB_URL",
+					ProjectSlug:            "test-project",
+					Environment:            "dev",
+					Version:                3,
+					ExpandSecretReferences: true,
+					StrictReferences:       true,
+					EscapeReferences:       true,
+				}
+
+				// When
+				secret, err := client.GetSecret(options)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if secret.SecretValue != "postgres://admin:${PASSWORD}@db" {
+					t.Errorf("unexpected secret: %v", secret)
+				}
+				if query.Get("expandSecretReferences") != "false" {
+					t.Errorf("unexpected query: %v", query)
+				}
+			},
+		},
+		{
+			"FailedWithUnknownReferenceExpandedByProvider",
+			func(t *testing.T) {
+				// Given
+				options := provider.GetSecretOptions{
+					SecretKey:              "DB_HOST",
+					ProjectSlug:            "test-project",
+					Environment:            "dev",
+					Version:                2,
+					ExpandSecretReferences: true,
+					StrictReferences:       true,
+				}
+
+				// When
+				_, err := client.GetSecret(options)
+
+				// Then
+				var referenceErr *provider.ReferenceError
+				if !errors.As(err, &referenceErr) || !errors.Is(err, provider.ErrUnknownReference) {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if referenceErr.Key != "DB_HOST" || strings.Join(referenceErr.Chain, " -> ") != "DB_HOST -> HOST" {
+					t.Errorf("unexpected error: %s", referenceErr)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithReferencesExpandedByServer",
+			func(t *testing.T) {
+				// Given
+				options := provider.GetSecretOptions{
+					SecretKey:                      "DB_URL",
+					ProjectSlug:                    "test-project",
+					Environment:                    "dev",
+					ExpandSecretReferencesByServer: true,
+				}
+
+				// When
+				secret, err := client.GetSecret(options)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if secret.SecretValue != "postgres://${USER}:$${PASSWORD}@db" {
+					t.Errorf("unexpected secret: %v", secret)
+				}
+				if query.Get("expandSecretReferences") != "true" {
+					t.Errorf("unexpected query: %v", query)
+				}
+			},
+		},
+		{
+			"FailedWithMissingSecret",
+			func(t *testing.T) {
+				// Given
+				options := provider.GetSecretOptions{
+					SecretKey:   "MISSING",
+					ProjectSlug: "test-project",
+					Environment: "dev",
+				}
+
+				// When
+				_, err := client.GetSecret(options)
+
+				// Then
+				var apiErr *infisical.APIError
+				if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+					t.Errorf("unexpected error: %v", err)
+				}
+			},
+		},
+		{
+			"FailedWithRetryAfter",
+			func(t *testing.T) {
+				// Given
+				options := provider.GetSecretOptions{
+					SecretKey:   "RATE_LIMITED",
+					ProjectSlug: "test-project",
+					Environment: "dev",
+				}
+
+				// When
+				_, err := client.GetSecret(options)
+
+				// Then
+				var apiErr *infisical.APIError
+				var retryAfterErr *provider.RetryAfterError
+				if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests || !errors.As(err, &retryAfterErr) || retryAfterErr.RetryAfter != 7*time.Second {
+					t.Errorf("unexpected error: %v", err)
+				}
+			},
+		},
+	} {
+		api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/api/v3/secrets/raw" {
+				// the latest secrets of the folder referenced by the secret
+				w.Header().Set("Content-Type", "application/json")
+				_ = json.NewEncoder(w).Encode(map[string]any{
+					"secrets": []map[string]any{{"secretKey": "USER", "secretValue": "admin"}},
+					"imports": []any{},
+				})
+				return
+			}
+			query = r.URL.Query()
+			if r.URL.Path == "/api/v3/secrets/raw/RATE_LIMITED" {
+				w.Header().Set("Retry-After", "7")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			secrets := map[string]map[string]any{
+				"DB_PASSWORD": {"secretKey": "DB_PASSWORD", "version": 12, "secretValue": "password"},
+				"DB_URL":      {"secretKey": "DB_URL", "version": 3, "secretValue": "postgres://${USER}:$${PASSWORD}@db"},
+				"DB_HOST":     {"secretKey": "DB_HOST", "version": 2, "secretValue": "${HOST}"},
+			}
+			secret, ok := secrets[strings.TrimPrefix(r.URL.Path, "/api/v3/secrets/raw/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"secret": secret,
+			})
+		}))
+		client = provider.NewInfisicalClient(infisical.Config{SiteUrl: api.URL})
+
+		t.Run(testcase.name, testcase.f)
+
+		api.Close()
+	}
+}
+
+func TestInfisicalClientCancelsRequests(t *testing.T) {
+	var (
+		api     *httptest.Server
+		client  provider.InfisicalClient
+		release chan struct{}
+	)
+
+	for _, testcase := range []struct {
+		name string
+		f    func(t *testing.T)
+	}{
+		{
+			"FailedWithListExceedingDeadline",
+			func(t *testing.T) {
+				// Given
+				ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+				defer cancel()
+				start := time.Now()
+
+				// When
+				_, err := client.WithContext(ctx).ListSecrets(provider.ListSecretsOptions{})
+
+				// Then
+				if !errors.Is(err, context.DeadlineExceeded) {
+					t.Errorf("unexpected error: %v", err)
+				}
+				if elapsed := time.Since(start); elapsed >= time.Second {
+					t.Errorf("waited beyond the deadline: %s", elapsed)
+				}
+			},
+		},
+		{
+			"FailedWithGetCanceled",
+			func(t *testing.T) {
+				// Given
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(50*time.Millisecond, cancel)
+
+				// When
+				_, err := client.WithContext(ctx).GetSecret(provider.GetSecretOptions{SecretKey: "DB_PASSWORD"})
+
+				// Then
+				if !errors.Is(err, context.Canceled) {
+					t.Errorf("unexpected error: %v", err)
+				}
+			},
+		},
+	} {
+		release = make(chan struct{})
+		api = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}))
+		client = provider.NewInfisicalClient(infisical.Config{SiteUrl: api.URL})
+
+		t.Run(testcase.name, testcase.f)
+
+		close(release)
+		api.Close()
+	}
+}
@@ -0,0 +1,194 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/config"
+	infisical "github.com/infisical/go-sdk"
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/secrets-store-csi-driver/provider/v1alpha1"
+)
+
+// formatters render secrets, keyed by their names, into the contents of a single file.
+// Entries are sorted by the names so that the contents are stable.
+var formatters = map[string]func(secrets map[string]string) ([]byte, error){
+	config.ObjectFormatDotenv:     formatDotenv,
+	config.ObjectFormatJSON:       formatJSON,
+	config.ObjectFormatYAML:       formatYAML,
+	config.ObjectFormatProperties: formatProperties,
+	config.ObjectFormatTOML:       formatTOML,
+}
+
+// aggregateFile renders the secrets selected by the object from the ones listed from the secrets path into a single file.
+func aggregateFile(mountConfig *config.MountConfig, object config.Object, secretsPath string, secrets []infisical.Secret, permission int32) (*v1alpha1.File, error) {
+	selected := map[string]string{}
+	for _, secret := range selectedSecrets(mountConfig, object, secretsPath, secrets) {
+		selected[secretPath(mountConfig, secretsPath, secret)] = secret.SecretValue
+	}
+	for _, name := range object.Names {
+		if _, ok := selected[name]; !ok {
+			return nil, fmt.Errorf("object %s not found in secrets", name)
+		}
+	}
+
+	filePath, err := mountConfig.ObjectFilePath(object, object.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store secrets, error: %w", err)
+	}
+	contents, err := formatters[object.Format](selected)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render %s in %s format, error: %w", filePath, object.Format, err)
+	}
+
+	return &v1alpha1.File{
+		Path:     filePath,
+		Mode:     object.FileMode(permission),
+		Contents: contents,
+	}, nil
+}
+
+// selectedSecrets returns the secrets the object selects.
+func selectedSecrets(mountConfig *config.MountConfig, object config.Object, secretsPath string, secrets []infisical.Secret) []infisical.Secret {
+	var selected []infisical.Secret
+	for _, secret := range secrets {
+		if object.Matches(secretPath(mountConfig, secretsPath, secret)) {
+			selected = append(selected, secret)
+		}
+	}
+	return selected
+}
+
+func sortedNames(secrets map[string]string) []string {
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var dotenvKeyRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// formatDotenv renders KEY='value' lines.
+// Values which cannot be single quoted are double quoted with \, ", $, `, CR and LF escaped,
+// so that sourcing the file by a shell neither expands nor substitutes commands.
+func formatDotenv(secrets map[string]string) ([]byte, error) {
+	var contents bytes.Buffer
+	for _, name := range sortedNames(secrets) {
+		if !dotenvKeyRegex.MatchString(name) {
+			return nil, fmt.Errorf("%s is not a valid dotenv key", name)
+		}
+		value := secrets[name]
+		if !strings.ContainsAny(value, "'\r\n") {
+			fmt.Fprintf(&contents, "%s='%s'\n", name, value)
+			continue
+		}
+		value = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`, "`", "\\`", "\r", `\r`, "\n", `\n`).Replace(value)
+		fmt.Fprintf(&contents, "%s=\"%s\"\n", name, value)
+	}
+	return contents.Bytes(), nil
+}
+
+func formatJSON(secrets map[string]string) ([]byte, error) {
+	contents, err := json.MarshalIndent(secrets, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(contents, '\n'), nil
+}
+
+func formatYAML(secrets map[string]string) ([]byte, error) {
+	if len(secrets) == 0 {
+		return []byte("{}\n"), nil
+	}
+	return yaml.Marshal(secrets)
+}
+
+// formatProperties renders key=value lines read by java.util.Properties.load.
+// Characters other than printable ASCII are escaped as \uXXXX, since the file is read as ISO 8859-1.
+func formatProperties(secrets map[string]string) ([]byte, error) {
+	escape := func(s string, key bool) string {
+		var escaped strings.Builder
+		for i, r := range s {
+			switch {
+			case r == '\\' || r == '=' || r == ':' || r == '#' || r == '!':
+				escaped.WriteString(`\` + string(r))
+			case r == ' ' && (key || i == 0):
+				escaped.WriteString(`\ `)
+			case r == '\t':
+				escaped.WriteString(`\t`)
+			case r == '\n':
+				escaped.WriteString(`\n`)
+			case r == '\r':
+				escaped.WriteString(`\r`)
+			case r == '\f':
+				escaped.WriteString(`\f`)
+			case r < 0x20 || r > 0x7e:
+				for _, c := range utf16.Encode([]rune{r}) {
+					fmt.Fprintf(&escaped, `\u%04X`, c)
+				}
+			default:
+				escaped.WriteRune(r)
+			}
+		}
+		return escaped.String()
+	}
+
+	var contents bytes.Buffer
+	for _, name := range sortedNames(secrets) {
+		fmt.Fprintf(&contents, "%s=%s\n", escape(name, true), escape(secrets[name], false))
+	}
+	return contents.Bytes(), nil
+}
+
+var tomlBareKeyRegex = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// formatTOML renders key = "value" lines with basic strings.
+func formatTOML(secrets map[string]string) ([]byte, error) {
+	quote := func(s string) string {
+		var quoted strings.Builder
+		quoted.WriteByte('"')
+		for _, r := range s {
+			switch r {
+			case '"':
+				quoted.WriteString(`\"`)
+			case '\\':
+				quoted.WriteString(`\\`)
+			case '\b':
+				quoted.WriteString(`\b`)
+			case '\t':
+				quoted.WriteString(`\t`)
+			case '\n':
+				quoted.WriteString(`\n`)
+			case '\f':
+				quoted.WriteString(`\f`)
+			case '\r':
+				quoted.WriteString(`\r`)
+			default:
+				if r < 0x20 || r == 0x7f {
+					fmt.Fprintf(&quoted, `\u%04X`, r)
+				} else {
+					quoted.WriteRune(r)
+				}
+			}
+		}
+		quoted.WriteByte('"')
+		return quoted.String()
+	}
+
+	var contents bytes.Buffer
+	for _, name := range sortedNames(secrets) {
+		key := name
+		if !tomlBareKeyRegex.MatchString(key) {
+			key = quote(key)
+		}
+		fmt.Fprintf(&contents, "%s = %s\n", key, quote(secrets[name]))
+	}
+	return contents.Bytes(), nil
+}
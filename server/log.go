@@ -0,0 +1,40 @@
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/config"
+	"sigs.k8s.io/secrets-store-csi-driver/provider/v1alpha1"
+)
+
+// mountRequest is the log representation of a MountRequest.
+// The node publish secrets are masked, and the attributes are logged as a MountConfig to drop credentials in them.
+type mountRequest struct {
+	*v1alpha1.MountRequest
+}
+
+var _ slog.LogValuer = mountRequest{}
+
+func (r mountRequest) LogValue() slog.Value {
+	var mountConfig config.MountConfig
+	_ = json.Unmarshal([]byte(r.GetAttributes()), &mountConfig)
+
+	attrs := []slog.Attr{
+		slog.Any("attributes", &mountConfig),
+		slog.String("targetPath", r.GetTargetPath()),
+		slog.String("permission", r.GetPermission()),
+	}
+	if r.GetSecrets() != "" {
+		attrs = append(attrs, slog.String("secrets", config.Redacted))
+	}
+	var objectVersions []slog.Attr
+	for _, objectVersion := range r.GetCurrentObjectVersion() {
+		objectVersions = append(objectVersions, slog.String(objectVersion.GetId(), objectVersion.GetVersion()))
+	}
+	if len(objectVersions) > 0 {
+		attrs = append(attrs, slog.Attr{Key: "currentObjectVersion", Value: slog.GroupValue(objectVersions...)})
+	}
+
+	return slog.GroupValue(attrs...)
+}
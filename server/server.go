@@ -3,19 +3,24 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
+	"net/http"
 	"os"
+	"path"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/auth"
 	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/config"
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/metrics"
 	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/provider"
 	"github.com/go-playground/validator/v10"
 	infisical "github.com/infisical/go-sdk"
 	"google.golang.org/grpc"
-	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/secrets-store-csi-driver/provider/v1alpha1"
 )
 
@@ -30,24 +35,61 @@ type CSIProviderServer struct {
 	grpcServer             *grpc.Server
 	listener               net.Listener
 	socketPath             string
+	siteUrl                string
 	auth                   auth.Auth
 	infisicalClientFactory provider.InfisicalClientFactory
 	validator              *validator.Validate
+	objectVersionKey       []byte
+	objectVersioner        *objectVersioner
+	lastKnownGood          *provider.LastKnownGoodCache
+	coalescer              *provider.ListSecretsCoalescer
+}
+
+// Option configures optional settings of CSIProviderServer.
+type Option func(*CSIProviderServer)
+
+// WithObjectVersionKey sets the HMAC key of object versions.
+// Without the key, values are not hashed and object versions are derived from the versions of the secrets in Infisical.
+func WithObjectVersionKey(key []byte) Option {
+	return func(s *CSIProviderServer) {
+		s.objectVersionKey = key
+	}
+}
+
+// WithLastKnownGoodCache serves the secrets last listed by the same identity from the cache while Infisical is unavailable.
+func WithLastKnownGoodCache(cache *provider.LastKnownGoodCache) Option {
+	return func(s *CSIProviderServer) {
+		s.lastKnownGood = cache
+	}
+}
+
+// WithListSecretsCoalescer shares the lists of secrets between mounts listing the same secrets with the same identity.
+func WithListSecretsCoalescer(coalescer *provider.ListSecretsCoalescer) Option {
+	return func(s *CSIProviderServer) {
+		s.coalescer = coalescer
+	}
 }
 
 var _ v1alpha1.CSIDriverProviderServer = &CSIProviderServer{}
 
 // NewCSIProviderServer returns a mock csi-provider grpc server
-func NewCSIProviderServer(version, socketPath string, auth auth.Auth, infisicalClientFactory provider.InfisicalClientFactory) *CSIProviderServer {
-	server := grpc.NewServer()
+// siteUrl is the Infisical site used when a SecretProviderClass does not specify one.
+// An empty siteUrl falls back to the Infisical SDK default.
+func NewCSIProviderServer(version, socketPath, siteUrl string, auth auth.Auth, infisicalClientFactory provider.InfisicalClientFactory, opts ...Option) *CSIProviderServer {
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(metrics.UnaryServerInterceptor))
 	s := &CSIProviderServer{
 		version:                version,
 		grpcServer:             server,
 		socketPath:             socketPath,
+		siteUrl:                siteUrl,
 		auth:                   auth,
 		infisicalClientFactory: infisicalClientFactory,
 		validator:              config.NewValidator(),
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.objectVersioner = newObjectVersioner(s.objectVersionKey)
 	v1alpha1.RegisterCSIDriverProviderServer(server, s)
 	return s
 }
@@ -76,7 +118,7 @@ func (s *CSIProviderServer) Mount(ctx context.Context, req *v1alpha1.MountReques
 		Error: &v1alpha1.Error{},
 	}
 
-	slog.Info("mount", "request", req)
+	slog.Info("mount", "request", mountRequest{req})
 
 	// parse request
 	mountConfig := config.NewMountConfig(*s.validator)
@@ -103,42 +145,76 @@ func (s *CSIProviderServer) Mount(ctx context.Context, req *v1alpha1.MountReques
 		mountResponse.Error.Code = ErrorInvalidSecretProviderClass
 		return mountResponse, fmt.Errorf("failed to get objects, error: %w", err)
 	}
-	if mountConfig.RawObjects != nil && len(objects) == 0 {
-		mountResponse.ObjectVersion = []*v1alpha1.ObjectVersion{
-			{
-				Id:      "NO_SECRETS",
-				Version: "0",
-			},
-		}
+	templates, err := mountConfig.Templates()
+	if err != nil {
+		mountResponse.Error.Code = ErrorInvalidSecretProviderClass
+		return mountResponse, fmt.Errorf("failed to get templates, error: %w", err)
+	}
+	if mountConfig.RawObjects != nil && len(objects) == 0 && len(templates) == 0 {
+		mountResponse.ObjectVersion = []*v1alpha1.ObjectVersion{noSecretsObjectVersion()}
 		return mountResponse, nil
 	}
 
-	// get credentials
-	kubeSecret := types.NamespacedName{
-		Namespace: mountConfig.AuthSecretNamespace,
-		Name:      mountConfig.AuthSecretName,
+	// get secrets
+	if mountConfig.SiteUrl == "" {
+		mountConfig.SiteUrl = s.siteUrl
 	}
-	credentials, err := s.auth.TokenFromKubeSecret(ctx, kubeSecret)
-	if err != nil {
-		mountResponse.Error.Code = ErrorBadRequest
-		return mountResponse, fmt.Errorf("failed to get credentials, error: %w", err)
+	// Infisical is called within a deadline earlier than the one of the mount, to serve the last known good secrets after it is exceeded
+	if s.lastKnownGood != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = withLastKnownGoodHeadroom(ctx)
+		defer cancel()
 	}
-
-	// get secrets
-	infisicalClient := s.infisicalClientFactory.NewClient(infisical.Config{})
-	if _, err := infisicalClient.UniversalAuthLogin(credentials.ID, credentials.Secret); err != nil {
-		mountResponse.Error.Code = ErrorUnauthorized
-		return mountResponse, fmt.Errorf("failed to login infisical, error: %w", err)
-	}
-	secrets, err := infisicalClient.ListSecrets(infisical.ListSecretsOptions{
-		ProjectSlug:            mountConfig.Project,
-		Environment:            mountConfig.Env,
-		SecretPath:             mountConfig.Path,
-		ExpandSecretReferences: true,
+	infisicalClient := s.infisicalClientFactory.NewClient(ctx, infisical.Config{
+		SiteUrl: mountConfig.SiteUrl,
 	})
-	if err != nil {
-		mountResponse.Error.Code = ErrorBadRequest
-		return mountResponse, fmt.Errorf("failed to list secrets, error: %w", err)
+	identity, loginCode, loginErr := s.login(ctx, infisicalClient, mountConfig)
+	if loginErr != nil {
+		if s.lastKnownGood == nil || identity == "" || !provider.IsUnavailable(loginErr) {
+			mountResponse.Error.Code = loginCode
+			return mountResponse, loginErr
+		}
+		// the secrets last listed by the identity are served while Infisical is unavailable, and the others fail with the login
+		infisicalClient = provider.NewLastKnownGoodInfisicalClient(provider.NewUnavailableInfisicalClient(loginErr), s.lastKnownGood, mountConfig.SiteUrl, identity)
+	} else if identity == "" {
+		if s.lastKnownGood != nil || s.coalescer != nil {
+			slog.Warn("secrets are neither cached nor shared since the credentials are not identified", "authMethod", mountConfig.AuthMethod)
+		}
+	} else {
+		if s.lastKnownGood != nil {
+			infisicalClient = provider.NewLastKnownGoodInfisicalClient(infisicalClient, s.lastKnownGood, mountConfig.SiteUrl, identity)
+		}
+		if s.coalescer != nil {
+			infisicalClient = provider.NewCoalescingInfisicalClient(ctx, infisicalClient, s.coalescer, mountConfig.SiteUrl, identity)
+		}
+	}
+	// secrets are listed once for each folder objects select secrets from,
+	// except for the folders other than the one of the mount whose objects are all pinned to versions, which are retrieved one by one
+	var sources []config.SecretsSource
+	if mountConfig.RawObjects == nil || len(templates) > 0 {
+		sources = append(sources, mountConfig.DefaultSource())
+	}
+	for _, object := range objects {
+		source := mountConfig.Source(object)
+		if object.PinnedVersion() != 0 && source != mountConfig.DefaultSource() {
+			continue
+		}
+		if !slices.Contains(sources, source) {
+			sources = append(sources, source)
+		}
+	}
+	listedSecrets := map[config.SecretsSource][]infisical.Secret{}
+	for _, source := range sources {
+		secrets, code, err := s.listSecrets(ctx, infisicalClient, mountConfig, source)
+		if err != nil && loginErr != nil {
+			mountResponse.Error.Code = loginCode
+			return mountResponse, loginErr
+		}
+		if err != nil {
+			mountResponse.Error.Code = code
+			return mountResponse, err
+		}
+		listedSecrets[source] = secrets
 	}
 
 	// store secrets
@@ -147,55 +223,256 @@ func (s *CSIProviderServer) Mount(ctx context.Context, req *v1alpha1.MountReques
 	if mountConfig.RawObjects == nil {
 		// all secrets
 		mode := int32(filePermission)
-		for _, secret := range secrets {
+		for _, secret := range listedSecrets[mountConfig.DefaultSource()] {
 			objectVersions = append(objectVersions, &v1alpha1.ObjectVersion{
-				Id:      secret.SecretKey,
-				Version: fmt.Sprint(secret.Version),
+				Id:      secretPath(mountConfig, mountConfig.Path, secret),
+				Version: s.objectVersioner.secretVersion(secret),
 			})
 
 			files = append(files, &v1alpha1.File{
-				Path:     secret.SecretKey,
+				Path:     secretPath(mountConfig, mountConfig.Path, secret),
 				Mode:     mode,
 				Contents: []byte(secret.SecretValue),
 			})
 		}
 	} else {
 		// specified secrets
-		secretsMap := map[string]infisical.Secret{}
-		for _, secret := range secrets {
-			secretsMap[secret.SecretKey] = secret
-		}
 		for _, object := range objects {
-			secret, ok := secretsMap[object.Name]
-			if !ok {
+			source := mountConfig.Source(object)
+			secrets := listedSecrets[source]
+			if object.IsAggregate() {
+				file, err := aggregateFile(mountConfig, object, source.Path, secrets, int32(filePermission))
+				if err != nil {
+					mountResponse.Error.Code = ErrorBadRequest
+					return mountResponse, err
+				}
+
+				objectVersions = append(objectVersions, &v1alpha1.ObjectVersion{
+					Id:      file.Path,
+					Version: s.objectVersioner.fileVersion(file.Contents, selectedSecrets(mountConfig, object, source.Path, secrets)),
+				})
+				files = append(files, file)
+				continue
+			}
+
+			matched := map[string]infisical.Secret{}
+			var names []string
+			if object.IsPattern() {
+				for _, secret := range secrets {
+					if name := secretPath(mountConfig, source.Path, secret); object.Matches(name) {
+						matched[name] = secret
+						names = append(names, name)
+					}
+				}
+			} else if secret, ok, err := namedSecret(infisicalClient, mountConfig, object, source, secrets); err != nil && loginErr != nil {
+				mountResponse.Error.Code = loginCode
+				return mountResponse, loginErr
+			} else if err != nil {
+				mountResponse.Error.Code = ErrorBadRequest
+				return mountResponse, fmt.Errorf("failed to get object %s, error: %w", object.Name, err)
+			} else if ok {
+				matched[object.Name] = secret
+				names = append(names, object.Name)
+			} else if object.Default != nil {
+				matched[object.Name] = infisical.Secret{SecretKey: path.Base(object.Name), SecretValue: *object.Default}
+				names = append(names, object.Name)
+			} else if object.Optional {
+				objectVersions = append(objectVersions, absentObjectVersion(objectID(mountConfig, source, object.Name)))
+			} else {
 				mountResponse.Error.Code = ErrorBadRequest
 				return mountResponse, fmt.Errorf("object %s not found in secrets", object.Name)
 			}
 
+			for _, name := range names {
+				secret := matched[name]
+				filePath, err := mountConfig.ObjectFilePath(object, name)
+				if err != nil {
+					mountResponse.Error.Code = ErrorBadRequest
+					return mountResponse, fmt.Errorf("failed to store secret %s, error: %w", name, err)
+				}
+				contents, err := object.Decode(secret.SecretValue)
+				if err != nil {
+					mountResponse.Error.Code = ErrorBadRequest
+					return mountResponse, fmt.Errorf("failed to decode object %s from %s, error: %w", name, object.Encoding, err)
+				}
+
+				objectVersions = append(objectVersions, &v1alpha1.ObjectVersion{
+					Id:      objectID(mountConfig, source, name),
+					Version: s.objectVersioner.secretVersion(secret),
+				})
+
+				files = append(files, &v1alpha1.File{
+					Path:     filePath,
+					Mode:     object.FileMode(int32(filePermission)),
+					Contents: contents,
+				})
+			}
+		}
+	}
+	if len(templates) > 0 {
+		values := map[string]string{}
+		for _, secret := range listedSecrets[mountConfig.DefaultSource()] {
+			values[secretPath(mountConfig, mountConfig.Path, secret)] = secret.SecretValue
+		}
+		for i, template := range templates {
+			contents, err := template.Render(values)
+			if err != nil {
+				mountResponse.Error.Code = ErrorBadRequest
+				return mountResponse, fmt.Errorf("failed to render templates, error: %w", config.NewConfigError("templates", fmt.Errorf("[%d].%w", i, err)))
+			}
+
 			objectVersions = append(objectVersions, &v1alpha1.ObjectVersion{
-				Id:      object.Name,
-				Version: fmt.Sprint(secret.Version),
+				Id:      template.Path,
+				Version: s.objectVersioner.fileVersion(contents, listedSecrets[mountConfig.DefaultSource()]),
 			})
 
 			files = append(files, &v1alpha1.File{
-				Path: func() string {
-					if object.Alias != "" {
-						return object.Alias
-					} else {
-						return object.Name
-					}
-				}(),
-				Mode:     int32(filePermission),
-				Contents: []byte(secret.SecretValue),
+				Path:     template.Path,
+				Mode:     template.FileMode(int32(filePermission)),
+				Contents: contents,
 			})
 		}
 	}
+
+	var filePaths []string
+	for _, file := range files {
+		filePaths = append(filePaths, file.Path)
+	}
+	if err := config.ValidateFilePaths(filePaths); err != nil {
+		mountResponse.Error.Code = ErrorBadRequest
+		return mountResponse, fmt.Errorf("failed to store secrets, error: %w", err)
+	}
+	if len(objectVersions) == 0 {
+		objectVersions = []*v1alpha1.ObjectVersion{noSecretsObjectVersion()}
+	}
 	mountResponse.ObjectVersion = objectVersions
 	mountResponse.Files = files
 
 	return mountResponse, nil
 }
 
+// listSecrets lists the secrets in the folder and returns the error code for the mount response on failure.
+// The client logs in again once when the cached token is rejected.
+func (s *CSIProviderServer) listSecrets(ctx context.Context, infisicalClient provider.InfisicalClient, mountConfig *config.MountConfig, source config.SecretsSource) ([]infisical.Secret, string, error) {
+	listSecretsOptions := provider.ListSecretsOptions{
+		ListSecretsOptions: infisical.ListSecretsOptions{
+			ProjectSlug:            source.Project,
+			Environment:            source.Env,
+			SecretPath:             source.Path,
+			ExpandSecretReferences: source.ExpandReferences == config.ExpandReferencesTrue,
+			IncludeImports:         mountConfig.IncludeImports,
+			Recursive:              mountConfig.Recursive,
+		},
+		TagSlugs:                       mountConfig.Tags(),
+		MatchAllTags:                   mountConfig.TagMatch == config.TagMatchAll,
+		ExpandSecretReferencesByServer: source.ExpandReferences == config.ExpandReferencesServer,
+		StrictReferences:               mountConfig.StrictReferences,
+		MaxReferenceDepth:              mountConfig.MaxReferenceDepth,
+		EscapeReferences:               mountConfig.EscapeReferences,
+	}
+	secrets, err := infisicalClient.ListSecrets(listSecretsOptions)
+	var apiErr *infisical.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+		// the cached token may have been revoked
+		s.auth.Invalidate(mountConfig)
+		if _, code, err := s.login(ctx, infisicalClient, mountConfig); err != nil {
+			return nil, code, err
+		}
+		secrets, err = infisicalClient.ListSecrets(listSecretsOptions)
+	}
+	var referenceErr *provider.ReferenceError
+	if errors.As(err, &referenceErr) {
+		return nil, ErrorBadRequest, fmt.Errorf("failed to expand secret %s, error: %w", referenceErr.Key, err)
+	}
+	if err != nil {
+		return nil, ErrorBadRequest, fmt.Errorf("failed to list secrets, error: %w", err)
+	}
+	return secrets, "", nil
+}
+
+// namedSecret returns the secret selected by the name of the object from the secrets listed from the source,
+// or retrieves it from Infisical when the object is pinned to a version, where references are expanded in the same way as the listed secrets.
+func namedSecret(client provider.InfisicalClient, mountConfig *config.MountConfig, object config.Object, source config.SecretsSource, secrets []infisical.Secret) (infisical.Secret, bool, error) {
+	version := object.PinnedVersion()
+	if version == 0 {
+		for _, secret := range secrets {
+			if secretPath(mountConfig, source.Path, secret) == object.Name {
+				return secret, true, nil
+			}
+		}
+		return infisical.Secret{}, false, nil
+	}
+
+	secret, err := client.GetSecret(provider.GetSecretOptions{
+		SecretKey:                      path.Base(object.Name),
+		ProjectSlug:                    source.Project,
+		Environment:                    source.Env,
+		SecretPath:                     path.Join(source.Path, path.Dir(object.Name)),
+		Version:                        version,
+		ExpandSecretReferences:         source.ExpandReferences == config.ExpandReferencesTrue,
+		ExpandSecretReferencesByServer: source.ExpandReferences == config.ExpandReferencesServer,
+		IncludeImports:                 mountConfig.IncludeImports,
+		StrictReferences:               mountConfig.StrictReferences,
+		MaxReferenceDepth:              mountConfig.MaxReferenceDepth,
+		EscapeReferences:               mountConfig.EscapeReferences,
+	})
+	var apiErr *infisical.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return infisical.Secret{}, false, nil
+	}
+	if err != nil {
+		return infisical.Secret{}, false, err
+	}
+	return secret, true, nil
+}
+
+// secretPath returns the path of the secret relative to the secrets path it is listed from.
+// It is the key of the secret unless the mount is recursive.
+func secretPath(mountConfig *config.MountConfig, secretsPath string, secret infisical.Secret) string {
+	if !mountConfig.Recursive {
+		return secret.SecretKey
+	}
+
+	folder := strings.TrimPrefix(path.Clean(secret.SecretPath), path.Clean(secretsPath))
+	return path.Join(strings.TrimPrefix(folder, "/"), secret.SecretKey)
+}
+
+// objectID returns the ID of the object version of the secret.
+// Secrets selected from other folders than the one of the mount are identified with the folder,
+// so that the same names in different folders do not share an ID.
+func objectID(mountConfig *config.MountConfig, source config.SecretsSource, name string) string {
+	if defaultSource := mountConfig.DefaultSource(); source.Project == defaultSource.Project && source.Env == defaultSource.Env && source.Path == defaultSource.Path {
+		return name
+	}
+	return path.Join(source.Project, source.Env, source.Path, name)
+}
+
+// login logs in the client and returns the identity of the credentials, or the error code for the mount response on failure.
+func (s *CSIProviderServer) login(ctx context.Context, infisicalClient provider.InfisicalClient, mountConfig *config.MountConfig) (identity, code string, err error) {
+	identity, err = s.auth.Login(ctx, infisicalClient, mountConfig)
+	if err != nil {
+		var credentialsErr *auth.CredentialsError
+		if errors.As(err, &credentialsErr) {
+			return identity, ErrorBadRequest, fmt.Errorf("failed to get credentials, error: %w", err)
+		}
+		return identity, ErrorUnauthorized, fmt.Errorf("failed to login infisical, error: %w", err)
+	}
+	return identity, "", nil
+}
+
+// lastKnownGoodHeadroom is the time left to a mount to serve the last known good secrets after calls to Infisical exceed their deadline.
+const lastKnownGoodHeadroom = time.Second
+
+// withLastKnownGoodHeadroom returns the context of ctx whose deadline is earlier than the one of ctx by lastKnownGoodHeadroom,
+// or by the half of the time left when it is shorter.
+func withLastKnownGoodHeadroom(ctx context.Context) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return ctx, func() {}
+	}
+	return context.WithDeadline(ctx, deadline.Add(-min(lastKnownGoodHeadroom, time.Until(deadline)/2)))
+}
+
 // Version implements provider csi-provider method
 func (m *CSIProviderServer) Version(ctx context.Context, req *v1alpha1.VersionRequest) (*v1alpha1.VersionResponse, error) {
 	return &v1alpha1.VersionResponse{
@@ -1,26 +1,38 @@
 package server_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/auth"
 	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/auth/mock_auth"
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/config"
+	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/provider"
 	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/provider/mock_provider"
 	"github.com/gidoichi/secrets-store-csi-driver-provider-infisical/server"
 	infisical "github.com/infisical/go-sdk"
-	api "github.com/infisical/go-sdk/packages/api/auth"
 	"github.com/infisical/go-sdk/packages/models"
 	"go.uber.org/mock/gomock"
 	"golang.org/x/mod/semver"
-	"k8s.io/apimachinery/pkg/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
 	"sigs.k8s.io/secrets-store-csi-driver/provider/v1alpha1"
 )
 
 const (
-	runtimeVersion = "0.0.1"
-	socketPath     = "/tmp/test.sock"
+	runtimeVersion   = "0.0.1"
+	socketPath       = "/tmp/test.sock"
+	objectVersionKey = "test-key"
 )
 
 var (
@@ -34,8 +46,6 @@ var (
 func TestCSIProviderServerMounts(t *testing.T) {
 	var (
 		idealMountRequest      *v1alpha1.MountRequest
-		idealKubeSecret        types.NamespacedName
-		idealCredentials       *auth.Credentials
 		expectedObjectVersions []*v1alpha1.ObjectVersion
 		expectedFiles          []*v1alpha1.File
 	)
@@ -48,14 +58,17 @@ func TestCSIProviderServerMounts(t *testing.T) {
 			"SuccessfullyWithSecrets",
 			func(t *testing.T) {
 				// Given
-				mockAuth.EXPECT().TokenFromKubeSecret(ctx, idealKubeSecret).Return(idealCredentials, nil)
-				mockInfisicalClientFactory.EXPECT().NewClient(infisical.Config{}).Return(mockInfisicalClient)
-				mockInfisicalClient.EXPECT().UniversalAuthLogin(idealCredentials.ID, idealCredentials.Secret)
-				mockInfisicalClient.EXPECT().ListSecrets(infisical.ListSecretsOptions{
-					ProjectSlug:            "test-project",
-					Environment:            "dev",
-					SecretPath:             "/",
-					ExpandSecretReferences: true,
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(provider.ListSecretsOptions{
+					ListSecretsOptions: infisical.ListSecretsOptions{
+						ProjectSlug:            "test-project",
+						Environment:            "dev",
+						SecretPath:             "/",
+						ExpandSecretReferences: true,
+						IncludeImports:         true,
+					},
+					StrictReferences: true,
 				}).Return([]models.Secret{
 					{
 						SecretKey:   "DB_USERNAME",
@@ -70,7 +83,7 @@ func TestCSIProviderServerMounts(t *testing.T) {
 				}, nil)
 
 				// When
-				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, mockAuth, mockInfisicalClientFactory)
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
 				actual, err := providerServer.Mount(ctx, idealMountRequest)
 
 				// Then
@@ -91,180 +104,1739 @@ func TestCSIProviderServerMounts(t *testing.T) {
 					t.Errorf("unexpected object versions: %v", actual.ObjectVersion)
 				}
 
-				if len(actual.Files) != 2 {
-					t.Errorf("unexpected files: %v", actual.Files)
-				} else if actual.Files[0].Path != expectedFiles[0].Path ||
-					actual.Files[0].Mode != expectedFiles[0].Mode ||
-					string(actual.Files[0].Contents) != string(expectedFiles[0].Contents) ||
-					actual.Files[1].Path != expectedFiles[1].Path ||
-					actual.Files[1].Mode != expectedFiles[1].Mode ||
-					string(actual.Files[1].Contents) != string(expectedFiles[1].Contents) {
-					t.Errorf("unexpected files: %v", actual.Files)
+				if len(actual.Files) != 2 {
+					t.Errorf("unexpected files: %v", actual.Files)
+				} else if actual.Files[0].Path != expectedFiles[0].Path ||
+					actual.Files[0].Mode != expectedFiles[0].Mode ||
+					string(actual.Files[0].Contents) != string(expectedFiles[0].Contents) ||
+					actual.Files[1].Path != expectedFiles[1].Path ||
+					actual.Files[1].Mode != expectedFiles[1].Mode ||
+					string(actual.Files[1].Contents) != string(expectedFiles[1].Contents) {
+					t.Errorf("unexpected files: %v", actual.Files)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithStableVersionsWithoutKey",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient).Times(2)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any()).Times(2)
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{
+					{
+						SecretKey:   "DB_PASSWORD",
+						Version:     1,
+						SecretValue: "password",
+					},
+				}, nil).Times(2)
+				expected, _ := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory).Mount(ctx, idealMountRequest)
+
+				// When
+				restarted := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory)
+				actual, err := restarted.Mount(ctx, idealMountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(actual.ObjectVersion) != 1 || len(expected.ObjectVersion) != 1 ||
+					actual.ObjectVersion[0].Version != expected.ObjectVersion[0].Version {
+					t.Errorf("unexpected object versions: %v, expected: %v", actual.ObjectVersion, expected.ObjectVersion)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithoutHashingValuesWithoutKey",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{
+					{
+						SecretKey:   "DB_PASSWORD",
+						Version:     3,
+						SecretValue: "password",
+					},
+				}, nil)
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory)
+
+				// When
+				actual, err := providerServer.Mount(ctx, idealMountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(actual.ObjectVersion) != 1 || actual.ObjectVersion[0].Version != "3" {
+					t.Errorf("unexpected object versions: %v", actual.ObjectVersion)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithAggregateVersionsOfSecretVersionsWithoutKey",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient).Times(3)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any()).Times(3)
+				gomock.InOrder(
+					mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{
+						{SecretKey: "DB_PASSWORD", Version: 1, SecretValue: "password"},
+						{SecretKey: "DB_USERNAME", Version: 1, SecretValue: "username"},
+					}, nil),
+					mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{
+						{SecretKey: "DB_USERNAME", Version: 1, SecretValue: "username"},
+						{SecretKey: "DB_PASSWORD", Version: 1, SecretValue: "password"},
+					}, nil),
+					mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{
+						{SecretKey: "DB_PASSWORD", Version: 2, SecretValue: "new-password"},
+						{SecretKey: "DB_USERNAME", Version: 1, SecretValue: "username"},
+					}, nil),
+				)
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace","objects":"- objectFormat: dotenv\n  objectAlias: .env\n"}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory)
+				first, _ := providerServer.Mount(ctx, mountRequest)
+				reordered, _ := providerServer.Mount(ctx, mountRequest)
+
+				// When
+				updated, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if reordered.ObjectVersion[0].Version != first.ObjectVersion[0].Version {
+					t.Errorf("unexpected version of reordered secrets: %s, expected: %s", reordered.ObjectVersion[0].Version, first.ObjectVersion[0].Version)
+				}
+				if updated.ObjectVersion[0].Version == first.ObjectVersion[0].Version {
+					t.Errorf("unexpected version of updated secrets: %s", updated.ObjectVersion[0].Version)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithMinimumConfiguredMountRequest",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(provider.ListSecretsOptions{
+					ListSecretsOptions: infisical.ListSecretsOptions{
+						ProjectSlug:            "test-project",
+						Environment:            "dev",
+						SecretPath:             "/",
+						ExpandSecretReferences: true,
+						IncludeImports:         true,
+					},
+					StrictReferences: true,
+				}).Return([]models.Secret{
+					{
+						SecretKey:   "DB_USERNAME",
+						Version:     1,
+						SecretValue: "admin",
+					},
+					{
+						SecretKey:   "DB_PASSWORD",
+						Version:     1,
+						SecretValue: "password",
+					},
+				}, nil)
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace"}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if actual.Error != nil && actual.Error.Code != "" {
+					t.Errorf("unexpected error: %v", actual.Error)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithSiteUrl",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{SiteUrl: "https://infisical.example.com"}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{}, nil)
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","siteUrl":"https://infisical.example.com","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace"}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "https://default.example.com", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if actual.Error != nil && actual.Error.Code != "" {
+					t.Errorf("unexpected error: %v", actual.Error)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithDefaultSiteUrl",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{SiteUrl: "https://default.example.com"}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{}, nil)
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "https://default.example.com", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, idealMountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if actual.Error != nil && actual.Error.Code != "" {
+					t.Errorf("unexpected error: %v", actual.Error)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithNoSecretsWhenEmptyObjectsGiven",
+			func(t *testing.T) {
+				// Given
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace","objects":""}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if actual.Error != nil && actual.Error.Code != "" {
+					t.Errorf("unexpected error: %v", actual.Error)
+				}
+				if len(actual.Files) != 0 {
+					t.Errorf("unexpected files: %v", actual.Files)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithAllSecretsWhenNoObjectsGiven",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(provider.ListSecretsOptions{
+					ListSecretsOptions: infisical.ListSecretsOptions{
+						ProjectSlug:            "test-project",
+						Environment:            "dev",
+						SecretPath:             "/",
+						ExpandSecretReferences: true,
+						IncludeImports:         true,
+					},
+					StrictReferences: true,
+				}).Return([]models.Secret{
+					{
+						SecretKey:   "DB_USERNAME",
+						Version:     1,
+						SecretValue: "admin",
+					},
+					{
+						SecretKey:   "DB_PASSWORD",
+						Version:     1,
+						SecretValue: "password",
+					},
+				}, nil)
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace"}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if actual.Error != nil && actual.Error.Code != "" {
+					t.Errorf("unexpected error: %v", actual.Error)
+				}
+				if len(actual.ObjectVersion) != 2 {
+					t.Errorf("unexpected object versions: %v", actual.ObjectVersion)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithSpecifiedSecretsWhenSomeObjectsGiven",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(provider.ListSecretsOptions{
+					ListSecretsOptions: infisical.ListSecretsOptions{
+						ProjectSlug:            "test-project",
+						Environment:            "dev",
+						SecretPath:             "/",
+						ExpandSecretReferences: true,
+						IncludeImports:         true,
+					},
+					StrictReferences: true,
+				}).Return([]models.Secret{
+					{
+						SecretKey:   "DB_USERNAME",
+						Version:     1,
+						SecretValue: "admin",
+					},
+					{
+						SecretKey:   "DB_PASSWORD",
+						Version:     1,
+						SecretValue: "password",
+					},
+				}, nil)
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace","objects":"- objectName: DB_USERNAME"}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if actual.Error != nil && actual.Error.Code != "" {
+					t.Errorf("unexpected error: %v", actual.Error)
+				}
+				if len(actual.ObjectVersion) != 1 {
+					t.Errorf("unexpected object versions: %v", actual.ObjectVersion)
+				} else if actual.ObjectVersion[0].Id != "DB_USERNAME" ||
+					actual.ObjectVersion[0].Version != "1-e0173abceea6e42f" {
+					t.Errorf("unexpected object versions: %v", actual.ObjectVersion)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithVersionChangedByReferencedSecret",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient).Times(2)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any()).Times(2)
+				gomock.InOrder(
+					mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{
+						{
+							SecretKey:   "DB_URL",
+							Version:     1,
+							SecretValue: "postgres://admin:password@db",
+						},
+					}, nil),
+					// the value of the secret referenced by DB_URL is updated
+					mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{
+						{
+							SecretKey:   "DB_URL",
+							Version:     1,
+							SecretValue: "postgres://admin:new-password@db",
+						},
+					}, nil),
+				)
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				before, _ := providerServer.Mount(ctx, idealMountRequest)
+
+				// When
+				actual, err := providerServer.Mount(ctx, idealMountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(actual.ObjectVersion) != 1 || len(before.ObjectVersion) != 1 ||
+					actual.ObjectVersion[0].Version == before.ObjectVersion[0].Version {
+					t.Errorf("unexpected object versions: %v, before: %v", actual.ObjectVersion, before.ObjectVersion)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithStableVersion",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient).Times(2)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any()).Times(2)
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{
+					{
+						SecretKey:   "DB_URL",
+						Version:     1,
+						SecretValue: "postgres://admin:password@db",
+					},
+				}, nil).Times(2)
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				before, _ := providerServer.Mount(ctx, idealMountRequest)
+
+				// When
+				actual, err := providerServer.Mount(ctx, idealMountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(actual.ObjectVersion) != 1 || len(before.ObjectVersion) != 1 ||
+					actual.ObjectVersion[0].Version != before.ObjectVersion[0].Version {
+					t.Errorf("unexpected object versions: %v, before: %v", actual.ObjectVersion, before.ObjectVersion)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithNoSecretsWhenNoSecretsListed",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{}, nil)
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, idealMountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(actual.ObjectVersion) != 1 ||
+					actual.ObjectVersion[0].Id != "NO_SECRETS" ||
+					actual.ObjectVersion[0].Version != "0" {
+					t.Errorf("unexpected object versions: %v", actual.ObjectVersion)
+				}
+				if len(actual.Files) != 0 {
+					t.Errorf("unexpected files: %v", actual.Files)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithRecursiveSecrets",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(provider.ListSecretsOptions{
+					ListSecretsOptions: infisical.ListSecretsOptions{
+						ProjectSlug:            "test-project",
+						Environment:            "dev",
+						SecretPath:             "/app",
+						ExpandSecretReferences: true,
+						IncludeImports:         true,
+						Recursive:              true,
+					},
+					StrictReferences: true,
+				}).Return([]models.Secret{
+					{
+						SecretKey:   "PASSWORD",
+						SecretPath:  "/app",
+						SecretValue: "root-password",
+					},
+					{
+						SecretKey:   "PASSWORD",
+						SecretPath:  "/app/db",
+						SecretValue: "db-password",
+					},
+					{
+						SecretKey:   "API_KEY",
+						SecretPath:  "/app/third-party/stripe",
+						SecretValue: "stripe-api-key",
+					},
+				}, nil)
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","secretsPath":"/app","recursive":"true","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace"}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(actual.Files) != 3 ||
+					actual.Files[0].Path != "PASSWORD" || string(actual.Files[0].Contents) != "root-password" ||
+					actual.Files[1].Path != "db/PASSWORD" || string(actual.Files[1].Contents) != "db-password" ||
+					actual.Files[2].Path != "third-party/stripe/API_KEY" || string(actual.Files[2].Contents) != "stripe-api-key" {
+					t.Errorf("unexpected files: %v", actual.Files)
+				}
+				if len(actual.ObjectVersion) != 3 || actual.ObjectVersion[1].Id != "db/PASSWORD" {
+					t.Errorf("unexpected object versions: %v", actual.ObjectVersion)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithRecursiveObjects",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{
+					{
+						SecretKey:   "PASSWORD",
+						SecretPath:  "/",
+						SecretValue: "root-password",
+					},
+					{
+						SecretKey:   "PASSWORD",
+						SecretPath:  "/db",
+						SecretValue: "db-password",
+					},
+				}, nil)
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","recursive":"true","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace","objects":"- objectName: db/PASSWORD\n  objectAlias: database/password"}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(actual.Files) != 1 ||
+					actual.Files[0].Path != "database/password" || string(actual.Files[0].Contents) != "db-password" {
+					t.Errorf("unexpected files: %v", actual.Files)
+				}
+			},
+		},
+		{
+			"FailedWithRecursivePathCollision",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{
+					{
+						SecretKey:   "db",
+						SecretPath:  "/",
+						SecretValue: "db",
+					},
+					{
+						SecretKey:   "PASSWORD",
+						SecretPath:  "/db",
+						SecretValue: "db-password",
+					},
+				}, nil)
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","recursive":"true","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace"}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, but got nil")
+				}
+				if actual.Error == nil || actual.Error.Code != server.ErrorBadRequest {
+					t.Errorf("unexpected error: %v", actual.Error)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithTags",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(provider.ListSecretsOptions{
+					ListSecretsOptions: infisical.ListSecretsOptions{
+						ProjectSlug:            "test-project",
+						Environment:            "dev",
+						SecretPath:             "/",
+						ExpandSecretReferences: true,
+						IncludeImports:         true,
+					},
+					TagSlugs:         []string{"payments-api", "orders-api"},
+					MatchAllTags:     true,
+					StrictReferences: true,
+				}).Return([]models.Secret{
+					{
+						SecretKey:   "SHARED",
+						SecretValue: "shared",
+					},
+				}, nil)
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","tagSlugs":"payments-api, orders-api","tagMatch":"all","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace"}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(actual.Files) != 1 ||
+					actual.Files[0].Path != "SHARED" || string(actual.Files[0].Contents) != "shared" {
+					t.Errorf("unexpected files: %v", actual.Files)
+				}
+			},
+		},
+		{
+			"FailedWithObjectExcludedByTags",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(provider.ListSecretsOptions{
+					ListSecretsOptions: infisical.ListSecretsOptions{
+						ProjectSlug:            "test-project",
+						Environment:            "dev",
+						SecretPath:             "/",
+						ExpandSecretReferences: true,
+						IncludeImports:         true,
+					},
+					TagSlugs:         []string{"payments-api"},
+					StrictReferences: true,
+				}).Return([]models.Secret{}, nil)
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","tagSlugs":"payments-api","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace","objects":"- objectName: UNTAGGED"}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, but got nil")
+				}
+				if actual.Error == nil || actual.Error.Code != server.ErrorBadRequest {
+					t.Errorf("unexpected error: %v", actual.Error)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithObjectNamePattern",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{
+					{
+						SecretKey:   "DB_USERNAME",
+						SecretValue: "admin",
+					},
+					{
+						SecretKey:   "API_KEY",
+						SecretValue: "api-key",
+					},
+					{
+						SecretKey:   "DB_PASSWORD",
+						SecretValue: "password",
+					},
+				}, nil)
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace","objects":"- objectNamePattern: ^DB_\n  objectAliasTemplate: '{{ .Name | trimPrefix \"DB_\" | lower }}'"}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(actual.Files) != 2 ||
+					actual.Files[0].Path != "username" || string(actual.Files[0].Contents) != "admin" ||
+					actual.Files[1].Path != "password" || string(actual.Files[1].Contents) != "password" {
+					t.Errorf("unexpected files: %v", actual.Files)
+				}
+				if len(actual.ObjectVersion) != 2 ||
+					actual.ObjectVersion[0].Id != "DB_USERNAME" ||
+					actual.ObjectVersion[1].Id != "DB_PASSWORD" {
+					t.Errorf("unexpected object versions: %v", actual.ObjectVersion)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithObjectNameGlobWhenRecursive",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{
+					{
+						SecretKey:   "PASSWORD",
+						SecretPath:  "/",
+						SecretValue: "root-password",
+					},
+					{
+						SecretKey:   "PASSWORD",
+						SecretPath:  "/db",
+						SecretValue: "db-password",
+					},
+				}, nil)
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","recursive":"true","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace","objects":"- objectNameGlob: db/*"}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(actual.Files) != 1 ||
+					actual.Files[0].Path != "db/PASSWORD" || string(actual.Files[0].Contents) != "db-password" {
+					t.Errorf("unexpected files: %v", actual.Files)
+				}
+			},
+		},
+		{
+			"FailedWithObjectAliasTemplateDuplicatingFilePaths",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{
+					{
+						SecretKey:   "DB_PASSWORD",
+						SecretValue: "password",
+					},
+					{
+						SecretKey:   "db_password",
+						SecretValue: "other-password",
+					},
+				}, nil)
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace","objects":"- objectNamePattern: (?i)^db_\n  objectAliasTemplate: '{{ .Name | lower }}'"}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, but got nil")
+				}
+				if actual.Error == nil || actual.Error.Code != server.ErrorBadRequest {
+					t.Errorf("unexpected error: %v", actual.Error)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithMissingOptionalObjectAndDefaultValue",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{
+					{
+						SecretKey:   "DB_PASSWORD",
+						Version:     1,
+						SecretValue: "password",
+					},
+				}, nil)
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace","objects":"- objectName: DB_PASSWORD\n  optional: true\n- objectName: FEATURE_FLAG\n  optional: true\n- objectName: LOG_LEVEL\n  default: info"}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(actual.Files) != 2 ||
+					actual.Files[0].Path != "DB_PASSWORD" || string(actual.Files[0].Contents) != "password" ||
+					actual.Files[1].Path != "LOG_LEVEL" || string(actual.Files[1].Contents) != "info" {
+					t.Errorf("unexpected files: %v", actual.Files)
+				}
+				if len(actual.ObjectVersion) != 3 ||
+					actual.ObjectVersion[0].Id != "DB_PASSWORD" || actual.ObjectVersion[0].Version != "1-4239f502aab1862a" ||
+					actual.ObjectVersion[1].Id != "FEATURE_FLAG" || actual.ObjectVersion[1].Version != "absent" ||
+					actual.ObjectVersion[2].Id != "LOG_LEVEL" || !strings.HasPrefix(actual.ObjectVersion[2].Version, "0-") {
+					t.Errorf("unexpected object versions: %v", actual.ObjectVersion)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithAggregateObjects",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{
+					{
+						SecretKey:   "DB_USERNAME",
+						SecretValue: "admin",
+					},
+					{
+						SecretKey:   "DB_PASSWORD",
+						SecretValue: "it's a \"$ecret\"\n",
+					},
+					{
+						SecretKey:   "GREETING",
+						SecretValue: "héllo = world",
+					},
+				}, nil)
+				objects := "" +
+					"- objectFormat: dotenv\n  objectNames: [DB_USERNAME, DB_PASSWORD]\n  objectAlias: .env\n  mode: \"0400\"\n" +
+					"- objectFormat: json\n  objectNamePattern: ^DB_\n  objectAlias: db.json\n" +
+					"- objectFormat: yaml\n  objectNames: [GREETING]\n  objectAlias: greeting.yaml\n" +
+					"- objectFormat: properties\n  objectNames: [GREETING]\n  objectAlias: greeting.properties\n" +
+					"- objectFormat: toml\n  objectAlias: all.toml"
+				attributes, _ := json.Marshal(map[string]string{
+					"projectSlug":         "test-project",
+					"envSlug":             "dev",
+					"authSecretName":      "test-infisical-credentials",
+					"authSecretNamespace": "test-namepace",
+					"objects":             objects,
+				})
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: string(attributes),
+					Secrets:    "{}",
+					Permission: "420",
+				}
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				expectedFiles := []*v1alpha1.File{
+					{
+						Path:     ".env",
+						Mode:     0400,
+						Contents: []byte("DB_PASSWORD=\"it's a \\\"\\$ecret\\\"\\n\"\nDB_USERNAME='admin'\n"),
+					},
+					{
+						Path:     "db.json",
+						Mode:     420,
+						Contents: []byte("{\n  \"DB_PASSWORD\": \"it's a \\\"$ecret\\\"\\n\",\n  \"DB_USERNAME\": \"admin\"\n}\n"),
+					},
+					{
+						Path:     "greeting.yaml",
+						Mode:     420,
+						Contents: []byte("GREETING: héllo = world\n"),
+					},
+					{
+						Path:     "greeting.properties",
+						Mode:     420,
+						Contents: []byte("GREETING=h\\u00E9llo \\= world\n"),
+					},
+					{
+						Path:     "all.toml",
+						Mode:     420,
+						Contents: []byte("DB_PASSWORD = \"it's a \\\"$ecret\\\"\\n\"\nDB_USERNAME = \"admin\"\nGREETING = \"héllo = world\"\n"),
+					},
+				}
+				if len(actual.Files) != len(expectedFiles) {
+					t.Fatalf("unexpected files: %v", actual.Files)
+				}
+				for i, expected := range expectedFiles {
+					if actual.Files[i].Path != expected.Path ||
+						actual.Files[i].Mode != expected.Mode ||
+						string(actual.Files[i].Contents) != string(expected.Contents) {
+						t.Errorf("unexpected file: %s %o %q", actual.Files[i].Path, actual.Files[i].Mode, actual.Files[i].Contents)
+					}
+				}
+				if len(actual.ObjectVersion) != len(expectedFiles) || actual.ObjectVersion[0].Id != ".env" {
+					t.Errorf("unexpected object versions: %v", actual.ObjectVersion)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithDotenvEscapingBackticks",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{
+					{
+						SecretKey:   "DB_PASSWORD",
+						SecretValue: "it's `whoami`",
+					},
+				}, nil)
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace","objects":"- objectFormat: dotenv\n  objectAlias: .env"}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				if len(actual.Files) != 1 || string(actual.Files[0].Contents) != "DB_PASSWORD=\"it's \\`whoami\\`\"\n" {
+					t.Errorf("unexpected files: %v", actual.Files)
+				}
+			},
+		},
+		{
+			"FailedWithAggregateObjectOfMissingSecret",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{
+					{
+						SecretKey:   "DB_USERNAME",
+						SecretValue: "admin",
+					},
+				}, nil)
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace","objects":"- objectFormat: dotenv\n  objectNames: [DB_USERNAME, DB_PASSWORD]\n  objectAlias: .env"}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, but got nil")
+				}
+				if actual.Error == nil || actual.Error.Code != server.ErrorBadRequest {
+					t.Errorf("unexpected error: %v", actual.Error)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithTemplates",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{
+					{
+						SecretKey:   "DB_HOST",
+						SecretValue: "db.example.com",
+					},
+					{
+						SecretKey:   "DB_USER",
+						SecretValue: "admin",
+					},
+					{
+						SecretKey:   "DB_PASSWORD",
+						SecretValue: "pass\"word",
+					},
+				}, nil)
+				templates := "" +
+					"- path: pgpass\n  mode: \"0600\"\n  template: '{{ .Secrets.DB_HOST }}:5432:*:{{ .Secrets.DB_USER }}:{{ required \"DB_PASSWORD is empty\" .Secrets.DB_PASSWORD }}'\n" +
+					"- path: config.txt\n  template: '{{ .Secrets.DB_USER | base64 }} {{ .Secrets.DB_PASSWORD | quote }} {{ .Secrets.DB_HOST | json }} {{ index .Secrets \"DB_PORT\" | default \"5432\" }}'"
+				attributes, _ := json.Marshal(map[string]string{
+					"projectSlug":         "test-project",
+					"envSlug":             "dev",
+					"authSecretName":      "test-infisical-credentials",
+					"authSecretNamespace": "test-namepace",
+					"objects":             "- objectName: DB_HOST",
+					"templates":           templates,
+				})
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: string(attributes),
+					Secrets:    "{}",
+					Permission: "420",
+				}
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err != nil {
+					t.Fatalf("unexpected error: %s", err)
+				}
+				if len(actual.Files) != 3 ||
+					actual.Files[0].Path != "DB_HOST" ||
+					actual.Files[1].Path != "pgpass" || actual.Files[1].Mode != 0600 ||
+					string(actual.Files[1].Contents) != "db.example.com:5432:*:admin:pass\"word" ||
+					actual.Files[2].Path != "config.txt" || actual.Files[2].Mode != 420 ||
+					string(actual.Files[2].Contents) != `YWRtaW4= "pass\"word" "db.example.com" 5432` {
+					t.Errorf("unexpected files: %v", actual.Files)
+				}
+				if len(actual.ObjectVersion) != 3 || actual.ObjectVersion[1].Id != "pgpass" || actual.ObjectVersion[2].Id != "config.txt" {
+					t.Errorf("unexpected object versions: %v", actual.ObjectVersion)
+				}
+			},
+		},
+		{
+			"FailedWithTemplateOfMissingSecret",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{
+					{
+						SecretKey:   "DB_USER",
+						SecretValue: "admin",
+					},
+				}, nil)
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace","objects":"","templates":"- path: url\n  template: '{{ .Secrets.DB_USER }}@{{ .Secrets.DB_HOST }}'"}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				var configErr *config.ConfigError
+				if !errors.As(err, &configErr) || !strings.HasPrefix(configErr.Error(), "templates: [0].template: ") || !strings.Contains(configErr.Error(), "DB_HOST") {
+					t.Errorf("unexpected error: %v", err)
+				}
+				if actual.Error == nil || actual.Error.Code != server.ErrorBadRequest {
+					t.Errorf("unexpected error: %v", actual.Error)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithEncodedObjects",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{
+					{
+						SecretKey:   "KEYSTORE",
+						SecretValue: "AAEC\n/w==",
+					},
+					{
+						SecretKey:   "KEYTAB",
+						SecretValue: "000102ff",
+					},
+					{
+						SecretKey:   "MESSAGE",
+						SecretValue: "AAEC/w==",
+					},
+				}, nil)
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace","objects":"- objectName: KEYSTORE\n  encoding: base64\n- objectName: KEYTAB\n  encoding: hex\n- objectName: MESSAGE\n  encoding: utf-8"}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(actual.Files) != 3 ||
+					!bytes.Equal(actual.Files[0].Contents, []byte{0x00, 0x01, 0x02, 0xff}) ||
+					!bytes.Equal(actual.Files[1].Contents, []byte{0x00, 0x01, 0x02, 0xff}) ||
+					string(actual.Files[2].Contents) != "AAEC/w==" {
+					t.Errorf("unexpected files: %v", actual.Files)
+				}
+			},
+		},
+		{
+			"FailedWithUndecodableObject",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{
+					{
+						SecretKey:   "KEYSTORE",
+						SecretValue: "not base64!",
+					},
+				}, nil)
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace","objects":"- objectName: KEYSTORE\n  encoding: base64"}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err == nil || !strings.Contains(err.Error(), "KEYSTORE") {
+					t.Errorf("unexpected error: %v", err)
+				}
+				if actual.Error == nil || actual.Error.Code != server.ErrorBadRequest {
+					t.Errorf("unexpected error: %v", actual.Error)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithFileModes",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{
+					{
+						SecretKey:   "SSH_KEY",
+						SecretValue: "ssh-key",
+					},
+					{
+						SecretKey:   "CA_BUNDLE",
+						SecretValue: "ca-bundle",
+					},
+					{
+						SecretKey:   "API_KEY",
+						SecretValue: "api-key",
+					},
+				}, nil)
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace","objects":"- objectName: SSH_KEY\n  mode: 0400\n- objectNamePattern: ^CA_\n  mode: \"0o444\"\n- objectName: API_KEY"}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(actual.Files) != 3 ||
+					actual.Files[0].Path != "SSH_KEY" || actual.Files[0].Mode != 0400 ||
+					actual.Files[1].Path != "CA_BUNDLE" || actual.Files[1].Mode != 0444 ||
+					actual.Files[2].Path != "API_KEY" || actual.Files[2].Mode != 420 {
+					t.Errorf("unexpected files: %v", actual.Files)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithPinnedVersions",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{
+					{
+						SecretKey:   "DB_USERNAME",
+						Version:     1,
+						SecretValue: "admin",
+					},
+					{
+						SecretKey:   "DB_PASSWORD",
+						Version:     13,
+						SecretValue: "new-password",
+					},
+				}, nil)
+				mockInfisicalClient.EXPECT().GetSecret(provider.GetSecretOptions{
+					SecretKey:              "DB_PASSWORD",
+					ProjectSlug:            "test-project",
+					Environment:            "dev",
+					SecretPath:             "/",
+					Version:                12,
+					ExpandSecretReferences: true,
+					IncludeImports:         true,
+					StrictReferences:       true,
+				}).Return(models.Secret{
+					SecretKey:   "DB_PASSWORD",
+					Version:     12,
+					SecretValue: "password",
+				}, nil)
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace","objects":"- objectName: DB_USERNAME\n  version: latest\n- objectName: DB_PASSWORD\n  version: 12"}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(actual.Files) != 2 ||
+					string(actual.Files[0].Contents) != "admin" ||
+					string(actual.Files[1].Contents) != "password" {
+					t.Errorf("unexpected files: %v", actual.Files)
+				}
+				if len(actual.ObjectVersion) != 2 ||
+					actual.ObjectVersion[0].Version != "1-e0173abceea6e42f" ||
+					actual.ObjectVersion[1].Version != "12-4239f502aab1862a" {
+					t.Errorf("unexpected object versions: %v", actual.ObjectVersion)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithoutListingFolderOfPinnedObjects",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				// only the folder of the mount is listed
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{
+					{SecretKey: "DB_USER", Version: 1, SecretValue: "admin"},
+				}, nil)
+				mockInfisicalClient.EXPECT().GetSecret(provider.GetSecretOptions{
+					SecretKey:              "API_KEY",
+					ProjectSlug:            "shared",
+					Environment:            "dev",
+					SecretPath:             "/api",
+					Version:                2,
+					ExpandSecretReferences: true,
+					IncludeImports:         true,
+					StrictReferences:       true,
+				}).Return(models.Secret{SecretKey: "API_KEY", Version: 2, SecretValue: "key"}, nil)
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace","objects":"` +
+						`- objectName: DB_USER\n` +
+						`- objectName: API_KEY\n  projectSlug: shared\n  secretsPath: /api\n  version: 2"}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(actual.Files) != 2 ||
+					string(actual.Files[0].Contents) != "admin" ||
+					string(actual.Files[1].Contents) != "key" {
+					t.Errorf("unexpected files: %v", actual.Files)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithMissingPinnedVersionOfOptionalObject",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{}, nil)
+				mockInfisicalClient.EXPECT().GetSecret(provider.GetSecretOptions{
+					SecretKey:              "PASSWORD",
+					ProjectSlug:            "test-project",
+					Environment:            "dev",
+					SecretPath:             "/db",
+					Version:                3,
+					ExpandSecretReferences: true,
+					IncludeImports:         true,
+					StrictReferences:       true,
+				}).Return(models.Secret{}, &infisical.APIError{StatusCode: http.StatusNotFound})
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","recursive":"true","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace","objects":"- objectName: db/PASSWORD\n  version: 3\n  optional: true"}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(actual.Files) != 0 {
+					t.Errorf("unexpected files: %v", actual.Files)
+				}
+				if len(actual.ObjectVersion) != 1 || actual.ObjectVersion[0].Id != "db/PASSWORD" || actual.ObjectVersion[0].Version != "absent" {
+					t.Errorf("unexpected object versions: %v", actual.ObjectVersion)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithObjectsFromOtherFolders",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				listSecretsOptions := func(project, env, secretsPath string) provider.ListSecretsOptions {
+					return provider.ListSecretsOptions{
+						ListSecretsOptions: infisical.ListSecretsOptions{
+							ProjectSlug:            project,
+							Environment:            env,
+							SecretPath:             secretsPath,
+							ExpandSecretReferences: true,
+							IncludeImports:         true,
+						},
+						StrictReferences: true,
+					}
+				}
+				// secrets are listed once for each folder
+				mockInfisicalClient.EXPECT().ListSecrets(listSecretsOptions("test-project", "dev", "/")).Return([]models.Secret{
+					{SecretKey: "DB_USER", SecretValue: "dev-admin"},
+				}, nil)
+				mockInfisicalClient.EXPECT().ListSecrets(listSecretsOptions("test-project", "prod", "/")).Return([]models.Secret{
+					{SecretKey: "DB_USER", SecretValue: "prod-admin"},
+					{SecretKey: "DB_PASSWORD", SecretValue: "prod-password"},
+				}, nil)
+				mockInfisicalClient.EXPECT().ListSecrets(listSecretsOptions("shared", "dev", "/api")).Return([]models.Secret{
+					{SecretKey: "API_KEY", SecretValue: "key"},
+				}, nil)
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace","objects":"` +
+						`- objectName: DB_USER\n` +
+						`- objectName: DB_USER\n  objectAlias: PROD_DB_USER\n  envSlug: prod\n` +
+						`- objectName: DB_PASSWORD\n  envSlug: prod\n` +
+						`- objectName: API_KEY\n  projectSlug: shared\n  secretsPath: /api"}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				expectedFiles := map[string]string{
+					"DB_USER":      "dev-admin",
+					"PROD_DB_USER": "prod-admin",
+					"DB_PASSWORD":  "prod-password",
+					"API_KEY":      "key",
+				}
+				if len(actual.Files) != len(expectedFiles) {
+					t.Errorf("unexpected files: %v", actual.Files)
+				}
+				for _, file := range actual.Files {
+					if expected, ok := expectedFiles[file.Path]; !ok || string(file.Contents) != expected {
+						t.Errorf("unexpected file: %v", file)
+					}
+				}
+				var ids []string
+				for _, objectVersion := range actual.ObjectVersion {
+					ids = append(ids, objectVersion.Id)
+				}
+				if strings.Join(ids, ",") != "DB_USER,test-project/prod/DB_USER,test-project/prod/DB_PASSWORD,shared/dev/api/API_KEY" {
+					t.Errorf("unexpected object versions: %v", actual.ObjectVersion)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithoutImports",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(provider.ListSecretsOptions{
+					ListSecretsOptions: infisical.ListSecretsOptions{
+						ProjectSlug:            "test-project",
+						Environment:            "dev",
+						SecretPath:             "/",
+						ExpandSecretReferences: true,
+						IncludeImports:         false,
+					},
+					StrictReferences: true,
+				}).Return([]models.Secret{}, nil)
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","includeImports":"false","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace"}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				_, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+			},
+		},
+		{
+			"SuccessfullyWithLastKnownGoodSecretsWhileUnavailable",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient).Times(2)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any()).Return("test-identity", nil).Times(2)
+				gomock.InOrder(
+					mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{{SecretKey: "DB_PASSWORD", SecretValue: "password"}}, nil),
+					mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return(nil, &infisical.APIError{StatusCode: http.StatusServiceUnavailable}),
+				)
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace"}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+				cache, _ := provider.NewLastKnownGoodCache(time.Hour, "", nil)
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)), server.WithLastKnownGoodCache(cache))
+				_, _ = providerServer.Mount(ctx, mountRequest)
+
+				// When
+				mountResponse, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(mountResponse.GetFiles()) != 1 || string(mountResponse.GetFiles()[0].GetContents()) != "password" {
+					t.Errorf("unexpected files: %v", mountResponse.GetFiles())
+				}
+			},
+		},
+		{
+			"SuccessfullyWithLastKnownGoodSecretsWhenLoginUnavailable",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient).Times(2)
+				gomock.InOrder(
+					mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any()).Return("test-identity", nil),
+					mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any()).Return("test-identity", &infisical.APIError{StatusCode: http.StatusServiceUnavailable}),
+				)
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{{SecretKey: "DB_PASSWORD", SecretValue: "password"}}, nil)
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace"}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+				cache, _ := provider.NewLastKnownGoodCache(time.Hour, "", nil)
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)), server.WithLastKnownGoodCache(cache))
+				_, _ = providerServer.Mount(ctx, mountRequest)
+
+				// When
+				mountResponse, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(mountResponse.GetFiles()) != 1 || string(mountResponse.GetFiles()[0].GetContents()) != "password" {
+					t.Errorf("unexpected files: %v", mountResponse.GetFiles())
+				}
+			},
+		},
+		{
+			"SuccessfullyWithLastKnownGoodSecretsWhenLoginExceedsDeadline",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(gomock.Any(), infisical.Config{}).Return(mockInfisicalClient).Times(2)
+				gomock.InOrder(
+					mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any()).Return("test-identity", nil),
+					mockAuth.EXPECT().Login(gomock.Any(), mockInfisicalClient, gomock.Any()).DoAndReturn(func(ctx context.Context, _ provider.InfisicalClient, _ *config.MountConfig) (string, error) {
+						<-ctx.Done()
+						return "test-identity", ctx.Err()
+					}),
+				)
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{{SecretKey: "DB_PASSWORD", SecretValue: "password"}}, nil)
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace"}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+				cache, _ := provider.NewLastKnownGoodCache(time.Hour, "", nil)
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)), server.WithLastKnownGoodCache(cache))
+				_, _ = providerServer.Mount(ctx, mountRequest)
+				mountCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+				defer cancel()
+
+				// When
+				mountResponse, err := providerServer.Mount(mountCtx, mountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if mountCtx.Err() != nil {
+					t.Errorf("unexpected mount exceeding the deadline")
+				}
+				if len(mountResponse.GetFiles()) != 1 || string(mountResponse.GetFiles()[0].GetContents()) != "password" {
+					t.Errorf("unexpected files: %v", mountResponse.GetFiles())
+				}
+			},
+		},
+		{
+			"FailedWithoutLastKnownGoodSecretsWhenLoginRejected",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient).Times(2)
+				gomock.InOrder(
+					mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any()).Return("test-identity", nil),
+					mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any()).Return("test-identity", &infisical.APIError{StatusCode: http.StatusUnauthorized}),
+				)
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{{SecretKey: "DB_PASSWORD", SecretValue: "password"}}, nil)
+				mountRequest := &v1alpha1.MountRequest{
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace"}`,
+					Secrets:    "{}",
+					Permission: "420",
+				}
+				cache, _ := provider.NewLastKnownGoodCache(time.Hour, "", nil)
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)), server.WithLastKnownGoodCache(cache))
+				_, _ = providerServer.Mount(ctx, mountRequest)
+
+				// When
+				mountResponse, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if mountResponse.GetError().GetCode() != server.ErrorUnauthorized {
+					t.Errorf("unexpected error code: %s", mountResponse.GetError().GetCode())
 				}
 			},
 		},
 		{
-			"SuccessfullyWithMinimumConfiguredMountRequest",
+			"FailedWithLoginUnavailableWithoutLastKnownGoodSecrets",
 			func(t *testing.T) {
 				// Given
-				mockAuth.EXPECT().TokenFromKubeSecret(ctx, idealKubeSecret).Return(idealCredentials, nil)
-				mockInfisicalClientFactory.EXPECT().NewClient(infisical.Config{}).Return(mockInfisicalClient)
-				mockInfisicalClient.EXPECT().UniversalAuthLogin(idealCredentials.ID, idealCredentials.Secret)
-				mockInfisicalClient.EXPECT().ListSecrets(infisical.ListSecretsOptions{
-					ProjectSlug:            "test-project",
-					Environment:            "dev",
-					SecretPath:             "/",
-					ExpandSecretReferences: true,
-				}).Return([]models.Secret{
-					{
-						SecretKey:   "DB_USERNAME",
-						Version:     1,
-						SecretValue: "admin",
-					},
-					{
-						SecretKey:   "DB_PASSWORD",
-						Version:     1,
-						SecretValue: "password",
-					},
-				}, nil)
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any()).Return("test-identity", &infisical.APIError{StatusCode: http.StatusServiceUnavailable})
 				mountRequest := &v1alpha1.MountRequest{
 					Attributes: `{"projectSlug":"test-project","envSlug":"dev","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace"}`,
 					Secrets:    "{}",
 					Permission: "420",
 				}
+				cache, _ := provider.NewLastKnownGoodCache(time.Hour, "", nil)
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)), server.WithLastKnownGoodCache(cache))
 
 				// When
-				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, mockAuth, mockInfisicalClientFactory)
-				actual, err := providerServer.Mount(ctx, mountRequest)
+				mountResponse, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err == nil {
+					t.Errorf("expected error, got nil")
+				}
+				if mountResponse.GetError().GetCode() != server.ErrorUnauthorized {
+					t.Errorf("unexpected error code: %s", mountResponse.GetError().GetCode())
+				}
+			},
+		},
+		{
+			"SuccessfullyWithSecretsInFolderPrioritizedOverImports",
+			func(t *testing.T) {
+				// Given
+				api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.Header().Set("Content-Type", "application/json")
+					_ = json.NewEncoder(w).Encode(map[string]any{
+						"secrets": []map[string]any{
+							{"secretKey": "DB_HOST", "secretValue": "folder"},
+						},
+						"imports": []map[string]any{
+							{"secrets": []map[string]any{
+								{"secretKey": "DB_HOST", "secretValue": "first-import"},
+								{"secretKey": "DB_PORT", "secretValue": "first-import"},
+							}},
+							{"secrets": []map[string]any{
+								{"secretKey": "DB_PORT", "secretValue": "second-import"},
+							}},
+						},
+					})
+				}))
+				defer api.Close()
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(provider.NewInfisicalClient(infisical.Config{SiteUrl: api.URL}))
+				mockAuth.EXPECT().Login(ctx, gomock.Any(), gomock.Any())
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, idealMountRequest)
 
 				// Then
 				if err != nil {
 					t.Errorf("unexpected error: %s", err)
 				}
-				if actual.Error != nil && actual.Error.Code != "" {
-					t.Errorf("unexpected error: %v", actual.Error)
+				files := map[string]string{}
+				for _, file := range actual.Files {
+					files[file.Path] = string(file.Contents)
+				}
+				if len(files) != 2 || files["DB_HOST"] != "folder" || files["DB_PORT"] != "first-import" {
+					t.Errorf("unexpected files: %v", files)
 				}
 			},
 		},
 		{
-			"SuccessfullyWithNoSecretsWhenEmptyObjectsGiven",
+			"SuccessfullyWithReferenceOptions",
 			func(t *testing.T) {
 				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(provider.ListSecretsOptions{
+					ListSecretsOptions: infisical.ListSecretsOptions{
+						ProjectSlug:            "test-project",
+						Environment:            "dev",
+						SecretPath:             "/",
+						ExpandSecretReferences: true,
+						IncludeImports:         true,
+					},
+					StrictReferences:  false,
+					MaxReferenceDepth: 3,
+				}).Return([]models.Secret{}, nil)
 				mountRequest := &v1alpha1.MountRequest{
-					Attributes: `{"projectSlug":"test-project","envSlug":"dev","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace","objects":""}`,
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","strictReferences":"false","maxReferenceDepth":"3","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace"}`,
 					Secrets:    "{}",
 					Permission: "420",
 				}
 
 				// When
-				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, mockAuth, mockInfisicalClientFactory)
-				actual, err := providerServer.Mount(ctx, mountRequest)
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				_, err := providerServer.Mount(ctx, mountRequest)
 
 				// Then
 				if err != nil {
 					t.Errorf("unexpected error: %s", err)
 				}
-				if actual.Error != nil && actual.Error.Code != "" {
-					t.Errorf("unexpected error: %v", actual.Error)
-				}
-				if len(actual.Files) != 0 {
-					t.Errorf("unexpected files: %v", actual.Files)
-				}
 			},
 		},
 		{
-			"SuccessfullyWithAllSecretsWhenNoObjectsGiven",
+			"SuccessfullyWithExpandReferencesOfObjects",
 			func(t *testing.T) {
 				// Given
-				mockAuth.EXPECT().TokenFromKubeSecret(ctx, idealKubeSecret).Return(idealCredentials, nil)
-				mockInfisicalClientFactory.EXPECT().NewClient(infisical.Config{}).Return(mockInfisicalClient)
-				mockInfisicalClient.EXPECT().UniversalAuthLogin(idealCredentials.ID, idealCredentials.Secret)
-				mockInfisicalClient.EXPECT().ListSecrets(infisical.ListSecretsOptions{
-					ProjectSlug:            "test-project",
-					Environment:            "dev",
-					SecretPath:             "/",
-					ExpandSecretReferences: true,
-				}).Return([]models.Secret{
-					{
-						SecretKey:   "DB_USERNAME",
-						Version:     1,
-						SecretValue: "admin",
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(provider.ListSecretsOptions{
+					ListSecretsOptions: infisical.ListSecretsOptions{
+						ProjectSlug:            "test-project",
+						Environment:            "dev",
+						SecretPath:             "/",
+						ExpandSecretReferences: false,
+						IncludeImports:         true,
 					},
-					{
-						SecretKey:   "DB_PASSWORD",
-						Version:     1,
-						SecretValue: "password",
+					ExpandSecretReferencesByServer: true,
+					StrictReferences:               true,
+				}).Return([]models.Secret{
+					{SecretKey: "DB_URL", SecretValue: "postgres://admin@db"},
+				}, nil)
+				mockInfisicalClient.EXPECT().ListSecrets(provider.ListSecretsOptions{
+					ListSecretsOptions: infisical.ListSecretsOptions{
+						ProjectSlug:            "test-project",
+						Environment:            "dev",
+						SecretPath:             "/",
+						ExpandSecretReferences: false,
+						IncludeImports:         true,
 					},
+					StrictReferences: true,
+				}).Return([]models.Secret{
+					{SecretKey: "DB_URL_TEMPLATE", SecretValue: "postgres://${DB_USER}@db"},
 				}, nil)
 				mountRequest := &v1alpha1.MountRequest{
-					Attributes: `{"projectSlug":"test-project","envSlug":"dev","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace"}`,
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","expandReferences":"server","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace","objects":"- objectName: DB_URL\n- objectName: DB_URL_TEMPLATE\n  expandReferences: false"}`,
 					Secrets:    "{}",
 					Permission: "420",
 				}
 
 				// When
-				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, mockAuth, mockInfisicalClientFactory)
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
 				actual, err := providerServer.Mount(ctx, mountRequest)
 
 				// Then
 				if err != nil {
 					t.Errorf("unexpected error: %s", err)
 				}
-				if actual.Error != nil && actual.Error.Code != "" {
-					t.Errorf("unexpected error: %v", actual.Error)
+				if len(actual.Files) != 2 ||
+					actual.Files[0].Path != "DB_URL" || string(actual.Files[0].Contents) != "postgres://admin@db" ||
+					actual.Files[1].Path != "DB_URL_TEMPLATE" || string(actual.Files[1].Contents) != "postgres://${DB_USER}@db" {
+					t.Errorf("unexpected files: %v", actual.Files)
 				}
-				if len(actual.ObjectVersion) != 2 {
+				if len(actual.ObjectVersion) != 2 || actual.ObjectVersion[0].Id != "DB_URL" || actual.ObjectVersion[1].Id != "DB_URL_TEMPLATE" {
 					t.Errorf("unexpected object versions: %v", actual.ObjectVersion)
 				}
 			},
 		},
 		{
-			"SuccessfullyWithSpecifiedSecretsWhenSomeObjectsGiven",
+			"FailedWithReferencesNotExpanded",
 			func(t *testing.T) {
 				// Given
-				mockAuth.EXPECT().TokenFromKubeSecret(ctx, idealKubeSecret).Return(idealCredentials, nil)
-				mockInfisicalClientFactory.EXPECT().NewClient(infisical.Config{}).Return(mockInfisicalClient)
-				mockInfisicalClient.EXPECT().UniversalAuthLogin(idealCredentials.ID, idealCredentials.Secret)
-				mockInfisicalClient.EXPECT().ListSecrets(infisical.ListSecretsOptions{
-					ProjectSlug:            "test-project",
-					Environment:            "dev",
-					SecretPath:             "/",
-					ExpandSecretReferences: true,
-				}).Return([]models.Secret{
-					{
-						SecretKey:   "DB_USERNAME",
-						Version:     1,
-						SecretValue: "admin",
-					},
-					{
-						SecretKey:   "DB_PASSWORD",
-						Version:     1,
-						SecretValue: "password",
-					},
-				}, nil)
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return(nil, &provider.ReferenceError{
+					Key:   "DB_URL",
+					Chain: []string{"DB_URL", "DB_HOST", "DB_URL"},
+					Err:   provider.ErrReferenceCycle,
+				})
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, idealMountRequest)
+
+				// Then
+				if err == nil || !strings.Contains(err.Error(), "DB_URL -> DB_HOST -> DB_URL") {
+					t.Errorf("unexpected error: %v", err)
+				}
+				if actual.Error == nil || actual.Error.Code != server.ErrorBadRequest {
+					t.Errorf("unexpected error: %v", actual.Error)
+				}
+			},
+		},
+		{
+			"FailedWithPinnedVersionNotRetrieved",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{}, nil)
+				mockInfisicalClient.EXPECT().GetSecret(gomock.Any()).Return(models.Secret{}, &infisical.APIError{StatusCode: http.StatusInternalServerError})
 				mountRequest := &v1alpha1.MountRequest{
-					Attributes: `{"projectSlug":"test-project","envSlug":"dev","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace","objects":"- objectName: DB_USERNAME"}`,
+					Attributes: `{"projectSlug":"test-project","envSlug":"dev","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace","objects":"- objectName: DB_PASSWORD\n  version: 12\n  optional: true"}`,
 					Secrets:    "{}",
 					Permission: "420",
 				}
 
 				// When
-				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, mockAuth, mockInfisicalClientFactory)
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
 				actual, err := providerServer.Mount(ctx, mountRequest)
 
 				// Then
-				if err != nil {
-					t.Errorf("unexpected error: %s", err)
+				if err == nil {
+					t.Errorf("expected error, but got nil")
 				}
-				if actual.Error != nil && actual.Error.Code != "" {
+				if actual.Error == nil || actual.Error.Code != server.ErrorBadRequest {
 					t.Errorf("unexpected error: %v", actual.Error)
 				}
-				if len(actual.ObjectVersion) != 1 {
-					t.Errorf("unexpected object versions: %v", actual.ObjectVersion)
-				} else if actual.ObjectVersion[0].Id != "DB_USERNAME" ||
-					actual.ObjectVersion[0].Version != "1" {
-					t.Errorf("unexpected object versions: %v", actual.ObjectVersion)
-				}
 			},
 		},
 		{
@@ -278,7 +1850,7 @@ func TestCSIProviderServerMounts(t *testing.T) {
 				}
 
 				// When
-				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, mockAuth, mockInfisicalClientFactory)
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
 				actual, err := providerServer.Mount(ctx, mountRequest)
 
 				// Then
@@ -294,14 +1866,17 @@ func TestCSIProviderServerMounts(t *testing.T) {
 			"FailedWithUnknownObjects",
 			func(t *testing.T) {
 				// Given
-				mockAuth.EXPECT().TokenFromKubeSecret(ctx, idealKubeSecret).Return(idealCredentials, nil)
-				mockInfisicalClientFactory.EXPECT().NewClient(infisical.Config{}).Return(mockInfisicalClient)
-				mockInfisicalClient.EXPECT().UniversalAuthLogin(idealCredentials.ID, idealCredentials.Secret)
-				mockInfisicalClient.EXPECT().ListSecrets(infisical.ListSecretsOptions{
-					ProjectSlug:            "test-project",
-					Environment:            "dev",
-					SecretPath:             "/",
-					ExpandSecretReferences: true,
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(provider.ListSecretsOptions{
+					ListSecretsOptions: infisical.ListSecretsOptions{
+						ProjectSlug:            "test-project",
+						Environment:            "dev",
+						SecretPath:             "/",
+						ExpandSecretReferences: true,
+						IncludeImports:         true,
+					},
+					StrictReferences: true,
 				}).Return([]models.Secret{
 					{
 						SecretKey:   "DB_USERNAME",
@@ -321,7 +1896,7 @@ func TestCSIProviderServerMounts(t *testing.T) {
 				}
 
 				// When
-				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, mockAuth, mockInfisicalClientFactory)
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
 				actual, err := providerServer.Mount(ctx, mountRequest)
 
 				// Then
@@ -340,7 +1915,7 @@ func TestCSIProviderServerMounts(t *testing.T) {
 				idealMountRequest.Attributes = "{}"
 
 				// When
-				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, mockAuth, mockInfisicalClientFactory)
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
 				actual, err := providerServer.Mount(context.Background(), idealMountRequest)
 
 				// Then
@@ -353,13 +1928,14 @@ func TestCSIProviderServerMounts(t *testing.T) {
 			},
 		},
 		{
-			"FailedWithoutKubeSecret",
+			"FailedWithoutCredentials",
 			func(t *testing.T) {
 				// Given
-				mockAuth.EXPECT().TokenFromKubeSecret(ctx, idealKubeSecret).Return(nil, errors.New("kube secret not found"))
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any()).Return("", auth.NewCredentialsError(errors.New("kube secret not found")))
 
 				// When
-				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, mockAuth, mockInfisicalClientFactory)
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
 				actual, err := providerServer.Mount(ctx, idealMountRequest)
 
 				// Then
@@ -372,15 +1948,14 @@ func TestCSIProviderServerMounts(t *testing.T) {
 			},
 		},
 		{
-			"FailedWithUniversalAuthLoginFailure",
+			"FailedWithLoginFailure",
 			func(t *testing.T) {
 				// Given
-				mockAuth.EXPECT().TokenFromKubeSecret(ctx, idealKubeSecret).Return(idealCredentials, nil)
-				mockInfisicalClientFactory.EXPECT().NewClient(infisical.Config{}).Return(mockInfisicalClient)
-				mockInfisicalClient.EXPECT().UniversalAuthLogin(idealCredentials.ID, idealCredentials.Secret).Return(api.MachineIdentityAuthLoginResponse{}, errors.New("failed to login"))
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any()).Return("", errors.New("failed to login"))
 
 				// When
-				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, mockAuth, mockInfisicalClientFactory)
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
 				actual, err := providerServer.Mount(ctx, idealMountRequest)
 
 				// Then
@@ -392,22 +1967,62 @@ func TestCSIProviderServerMounts(t *testing.T) {
 				}
 			},
 		},
+		{
+			"SuccessfullyWithLoginAgainAfterUnauthorized",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				gomock.InOrder(
+					mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any()),
+					mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return(nil, &infisical.APIError{StatusCode: http.StatusUnauthorized}),
+					mockAuth.EXPECT().Invalidate(gomock.Any()),
+					mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any()),
+					mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{
+						{
+							SecretKey:   "DB_USERNAME",
+							Version:     1,
+							SecretValue: "admin",
+						},
+						{
+							SecretKey:   "DB_PASSWORD",
+							Version:     1,
+							SecretValue: "password",
+						},
+					}, nil),
+				)
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
+				actual, err := providerServer.Mount(ctx, idealMountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				if len(actual.Files) != 2 {
+					t.Errorf("unexpected files: %v", actual.Files)
+				}
+			},
+		},
 		{
 			"FailedWithListSecretFailure",
 			func(t *testing.T) {
 				// Given
-				mockAuth.EXPECT().TokenFromKubeSecret(ctx, idealKubeSecret).Return(idealCredentials, nil)
-				mockInfisicalClientFactory.EXPECT().NewClient(infisical.Config{}).Return(mockInfisicalClient)
-				mockInfisicalClient.EXPECT().UniversalAuthLogin(idealCredentials.ID, idealCredentials.Secret).Return(api.MachineIdentityAuthLoginResponse{}, nil)
-				mockInfisicalClient.EXPECT().ListSecrets(infisical.ListSecretsOptions{
-					ProjectSlug:            "test-project",
-					Environment:            "dev",
-					SecretPath:             "/",
-					ExpandSecretReferences: true,
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(provider.ListSecretsOptions{
+					ListSecretsOptions: infisical.ListSecretsOptions{
+						ProjectSlug:            "test-project",
+						Environment:            "dev",
+						SecretPath:             "/",
+						ExpandSecretReferences: true,
+						IncludeImports:         true,
+					},
+					StrictReferences: true,
 				}).Return(nil, errors.New("failed to list secrets"))
 
 				// When
-				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, mockAuth, mockInfisicalClientFactory)
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)))
 				actual, err := providerServer.Mount(ctx, idealMountRequest)
 
 				// Then
@@ -430,22 +2045,14 @@ func TestCSIProviderServerMounts(t *testing.T) {
 			Secrets:    "{}",
 			Permission: "420",
 		}
-		idealKubeSecret = types.NamespacedName{
-			Namespace: "test-namepace",
-			Name:      "test-infisical-credentials",
-		}
-		idealCredentials = &auth.Credentials{
-			ID:     "test-client-id",
-			Secret: "test-client-secret",
-		}
 		expectedObjectVersions = []*v1alpha1.ObjectVersion{
 			{
 				Id:      "DB_USERNAME",
-				Version: "1",
+				Version: "1-e0173abceea6e42f",
 			},
 			{
 				Id:      "DB_PASSWORD",
-				Version: "1",
+				Version: "1-4239f502aab1862a",
 			},
 		}
 		expectedFiles = []*v1alpha1.File{
@@ -465,6 +2072,125 @@ func TestCSIProviderServerMounts(t *testing.T) {
 	}
 }
 
+func TestCSIProviderServerCoalescesMounts(t *testing.T) {
+	var (
+		mountRequest *v1alpha1.MountRequest
+		mounts       int
+	)
+
+	for _, testcase := range []struct {
+		name string
+		f    func(t *testing.T)
+	}{
+		{
+			"SuccessfullyWithOneLoginAndOneListForConcurrentMounts",
+			func(t *testing.T) {
+				// Given
+				kubeClient := fake.NewSimpleClientset(&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: "test-namepace",
+						Name:      "test-infisical-credentials",
+					},
+					Data: map[string][]byte{
+						"client-id":     []byte("test-client-id"),
+						"client-secret": []byte("test-client-secret"),
+					},
+				})
+				release := make(chan struct{})
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient).Times(mounts)
+				mockInfisicalClient.EXPECT().SetAccessToken("test-access-token").Times(mounts - 1)
+				mockInfisicalClient.EXPECT().UniversalAuthLogin("test-client-id", "test-client-secret").DoAndReturn(func(string, string) (infisical.MachineIdentityCredential, error) {
+					<-release
+					return infisical.MachineIdentityCredential{AccessToken: "test-access-token", ExpiresIn: 3600}, nil
+				}).Times(1)
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{{SecretKey: "DB_PASSWORD", SecretValue: "password"}}, nil).Times(1)
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", auth.NewAuth(kubeClient), mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)), server.WithListSecretsCoalescer(provider.NewListSecretsCoalescer(time.Minute)))
+				var mounted sync.WaitGroup
+				responses := make([]*v1alpha1.MountResponse, mounts)
+				errs := make([]error, mounts)
+
+				// When
+				for i := range mounts {
+					mounted.Add(1)
+					go func() {
+						defer mounted.Done()
+						responses[i], errs[i] = providerServer.Mount(ctx, mountRequest)
+					}()
+				}
+				time.Sleep(100 * time.Millisecond)
+				close(release)
+				mounted.Wait()
+
+				// Then
+				for i := range mounts {
+					if errs[i] != nil {
+						t.Errorf("unexpected error: %s", errs[i])
+						continue
+					}
+					if files := responses[i].GetFiles(); len(files) != 1 || string(files[0].GetContents()) != "password" {
+						t.Errorf("unexpected files: %v", files)
+					}
+				}
+			},
+		},
+		{
+			"SuccessfullyWithOneListForEachIdentity",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient).Times(mounts)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any()).DoAndReturn(func(_ context.Context, _ provider.InfisicalClient, mountConfig *config.MountConfig) (string, error) {
+					return mountConfig.AuthSecretName, nil
+				}).Times(mounts)
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{{SecretKey: "DB_PASSWORD", SecretValue: "password"}}, nil).Times(2)
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory, server.WithObjectVersionKey([]byte(objectVersionKey)), server.WithListSecretsCoalescer(provider.NewListSecretsCoalescer(time.Minute)))
+				anotherMountRequest := &v1alpha1.MountRequest{
+					Attributes: strings.Replace(mountRequest.Attributes, "test-infisical-credentials", "another-infisical-credentials", 1),
+					Secrets:    mountRequest.Secrets,
+					Permission: mountRequest.Permission,
+				}
+				var mounted sync.WaitGroup
+				errs := make([]error, mounts)
+
+				// When
+				for i := range mounts {
+					mounted.Add(1)
+					go func() {
+						defer mounted.Done()
+						request := mountRequest
+						if i%2 == 1 {
+							request = anotherMountRequest
+						}
+						_, errs[i] = providerServer.Mount(ctx, request)
+					}()
+				}
+				mounted.Wait()
+
+				// Then
+				for i := range mounts {
+					if errs[i] != nil {
+						t.Errorf("unexpected error: %s", errs[i])
+					}
+				}
+			},
+		},
+	} {
+		ctx = context.Background()
+		ctrl = gomock.NewController(t)
+		mockAuth = mock_auth.NewMockAuth(ctrl)
+		mockInfisicalClientFactory = mock_provider.NewMockInfisicalClientFactory(ctrl)
+		mockInfisicalClient = mock_provider.NewMockInfisicalClient(ctrl)
+		mockInfisicalClient.EXPECT().WithContext(gomock.Any()).Return(mockInfisicalClient).AnyTimes()
+		mountRequest = &v1alpha1.MountRequest{
+			Attributes: `{"projectSlug":"test-project","envSlug":"dev","secretsPath":"/","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namepace"}`,
+			Secrets:    "{}",
+			Permission: "420",
+		}
+		mounts = 50
+
+		t.Run(testcase.name, testcase.f)
+	}
+}
+
 func TestCSIProviderServerVersion(t *testing.T) {
 	var (
 		idealVersionRequest *v1alpha1.VersionRequest
@@ -480,7 +2206,7 @@ func TestCSIProviderServerVersion(t *testing.T) {
 				// Given
 
 				// When
-				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, mockAuth, mockInfisicalClientFactory)
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory)
 				actual, err := providerServer.Version(ctx, idealVersionRequest)
 
 				// Then
@@ -504,3 +2230,104 @@ func TestCSIProviderServerVersion(t *testing.T) {
 		t.Run(testcase.name, testcase.f)
 	}
 }
+
+func TestCSIProviderServerMountLogs(t *testing.T) {
+	var (
+		logs         *bytes.Buffer
+		mountRequest *v1alpha1.MountRequest
+	)
+
+	for _, testcase := range []struct {
+		name string
+		f    func(t *testing.T)
+	}{
+		{
+			"SuccessfullyWithoutCredentials",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{
+					{
+						SecretKey:   "DB_PASSWORD",
+						Version:     1,
+						SecretValue: "test-secret-value",
+					},
+				}, nil)
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory)
+				_, err := providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				if err != nil {
+					t.Errorf("unexpected error: %s", err)
+				}
+				for _, credential := range []string{"test-node-publish-secret", "test-service-account-token", "test-secret-value"} {
+					if strings.Contains(logs.String(), credential) {
+						t.Errorf("credential %s found in logs: %s", credential, logs)
+					}
+				}
+			},
+		},
+		{
+			"SuccessfullyWithoutCredentialsOnFailure",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any()).Return("", errors.New("failed to login"))
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory)
+				_, _ = providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				for _, credential := range []string{"test-node-publish-secret", "test-service-account-token"} {
+					if strings.Contains(logs.String(), credential) {
+						t.Errorf("credential %s found in logs: %s", credential, logs)
+					}
+				}
+			},
+		},
+		{
+			"SuccessfullyWithMountIdentification",
+			func(t *testing.T) {
+				// Given
+				mockInfisicalClientFactory.EXPECT().NewClient(ctx, infisical.Config{}).Return(mockInfisicalClient)
+				mockAuth.EXPECT().Login(ctx, mockInfisicalClient, gomock.Any())
+				mockInfisicalClient.EXPECT().ListSecrets(gomock.Any()).Return([]models.Secret{}, nil)
+
+				// When
+				providerServer := server.NewCSIProviderServer(runtimeVersion, socketPath, "", mockAuth, mockInfisicalClientFactory)
+				_, _ = providerServer.Mount(ctx, mountRequest)
+
+				// Then
+				for _, value := range []string{"test-pod", "test-namespace", "test-spc", "test-project", "dev", config.Redacted} {
+					if !strings.Contains(logs.String(), value) {
+						t.Errorf("%s not found in logs: %s", value, logs)
+					}
+				}
+			},
+		},
+	} {
+		ctx = context.Background()
+		ctrl = gomock.NewController(t)
+		mockAuth = mock_auth.NewMockAuth(ctrl)
+		mockInfisicalClientFactory = mock_provider.NewMockInfisicalClientFactory(ctrl)
+		mockInfisicalClient = mock_provider.NewMockInfisicalClient(ctrl)
+		mountRequest = &v1alpha1.MountRequest{
+			Attributes: `{"projectSlug":"test-project","envSlug":"dev","authSecretName":"test-infisical-credentials","authSecretNamespace":"test-namespace",` +
+				`"csi.storage.k8s.io/pod.name":"test-pod","csi.storage.k8s.io/pod.namespace":"test-namespace","secretProviderClass":"test-spc",` +
+				`"csi.storage.k8s.io/serviceAccount.tokens":"{\"infisical\":{\"token\":\"test-service-account-token\"}}"}`,
+			Secrets:    `{"client-secret":"test-node-publish-secret"}`,
+			Permission: "420",
+		}
+		logs = &bytes.Buffer{}
+		defaultLogger := slog.Default()
+		slog.SetDefault(slog.New(slog.NewJSONHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+		t.Run(testcase.name, testcase.f)
+
+		slog.SetDefault(defaultLogger)
+	}
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	infisical "github.com/infisical/go-sdk"
+	"sigs.k8s.io/secrets-store-csi-driver/provider/v1alpha1"
+)
+
+// noSecretsObjectVersion is reported for a mount without secrets, so that the rotation reconciler always has a stable version to compare.
+func noSecretsObjectVersion() *v1alpha1.ObjectVersion {
+	return &v1alpha1.ObjectVersion{
+		Id:      "NO_SECRETS",
+		Version: "0",
+	}
+}
+
+// absentObjectVersion is reported for an optional object not found in Infisical, so that the rotation reconciler notices when it is added.
+func absentObjectVersion(id string) *v1alpha1.ObjectVersion {
+	return &v1alpha1.ObjectVersion{
+		Id:      id,
+		Version: "absent",
+	}
+}
+
+// objectVersioner versions mounted objects.
+// With a key, versions are HMACs of the contents, which change whenever the contents change,
+// including the values of referenced secrets expanded into them,
+// while the contents cannot be guessed from the versions stored in SecretProviderClassPodStatus.
+// Without a key, values are never hashed and versions are derived from the versions of the secrets in Infisical,
+// which do not change when only referenced secrets expanded into the values change.
+type objectVersioner struct {
+	key []byte
+}
+
+// newObjectVersioner returns an objectVersioner with the key, which may be empty.
+func newObjectVersioner(key []byte) *objectVersioner {
+	return &objectVersioner{
+		key: key,
+	}
+}
+
+// secretVersion returns the version of the secret in Infisical, followed by the HMAC of its value with a key.
+// The version of a default value, which is not in Infisical, is 0.
+func (v *objectVersioner) secretVersion(secret infisical.Secret) string {
+	if len(v.key) == 0 {
+		return strconv.Itoa(secret.Version)
+	}
+	return fmt.Sprintf("%d-%s", secret.Version, v.version([]byte(secret.SecretValue)))
+}
+
+// fileVersion returns the version of the contents rendered from the secrets,
+// which is the HMAC of the contents with a key, or the digest of the versions of the secrets in Infisical otherwise.
+func (v *objectVersioner) fileVersion(contents []byte, secrets []infisical.Secret) string {
+	if len(v.key) > 0 {
+		return v.version(contents)
+	}
+
+	versions := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		versions = append(versions, strings.Join([]string{secret.Environment, secret.SecretPath, secret.SecretKey, secret.ID, strconv.Itoa(secret.Version)}, "\x00"))
+	}
+	slices.Sort(versions)
+	digest := sha256.New()
+	for _, version := range versions {
+		digest.Write([]byte(version))
+		digest.Write([]byte{0})
+	}
+	return hex.EncodeToString(digest.Sum(nil))[:16]
+}
+
+func (v *objectVersioner) version(contents []byte) string {
+	mac := hmac.New(sha256.New, v.key)
+	mac.Write(contents)
+	return hex.EncodeToString(mac.Sum(nil))[:16]
+}
@@ -29,6 +29,32 @@ export API_VERSION="$(get_secrets_store_api_version)"
 
 export ENV_SLUG="${ENV_SLUG:-dev}"
 
+# secret created and updated in Infisical by the `rotate` tests
+export ROTATION_SECRET_NAME="${ROTATION_SECRET_NAME:-E2E_ROTATION}"
+INFISICAL_API_URL="${INFISICAL_API_URL:-https://app.infisical.com/api}"
+
+infisical_access_token() {
+    curl -sSf -X POST "$INFISICAL_API_URL/v1/auth/universal-auth/login" \
+        -H 'Content-Type: application/json' \
+        -d "$(jq -n --arg id "$CLIENT_ID" --arg secret "$CLIENT_SECRET" '{clientId: $id, clientSecret: $secret}')" \
+        | jq -r .accessToken
+}
+
+# infisical_set_secret <method> <value> creates (POST) or updates (PATCH) the rotation secret
+infisical_set_secret() {
+    curl -sSf -X "$1" "$INFISICAL_API_URL/v3/secrets/raw/$ROTATION_SECRET_NAME" \
+        -H "Authorization: Bearer $(infisical_access_token)" \
+        -H 'Content-Type: application/json' \
+        -d "$(jq -n --arg project "$PROJECT_ID" --arg env "$ENV_SLUG" --arg value "$2" '{workspaceId: $project, environment: $env, secretPath: "/", secretValue: $value, type: "shared"}')"
+}
+
+infisical_delete_secret() {
+    curl -sSf -X DELETE "$INFISICAL_API_URL/v3/secrets/raw/$ROTATION_SECRET_NAME" \
+        -H "Authorization: Bearer $(infisical_access_token)" \
+        -H 'Content-Type: application/json' \
+        -d "$(jq -n --arg project "$PROJECT_ID" --arg env "$ENV_SLUG" '{workspaceId: $project, environment: $env, secretPath: "/", type: "shared"}')"
+}
+
 teardown_file() {
     # for `init`
     helm uninstall secrets-store-csi-driver-provider-infisical -n "$PROVIDER_NAMESPACE" || true
@@ -55,6 +81,13 @@ teardown_file() {
     # for `multiple`
     envsubst < "$BATS_TESTS_DIR/infisical_v1_multiple_secretproviderclass.yaml" | kubectl delete -n "$NAMESPACE" -f - || true
     envsubst < "$E2E_PROVIDER_TESTS_DIR/pod-e2e-provider-inline-volume-multiple-spc.yaml" | kubectl delete -n "$NAMESPACE" -f - || true
+
+    # for `rotate`
+    envsubst < "$BATS_TESTS_DIR/pod-rotation.yaml" | kubectl delete -n "$NAMESPACE" -f - || true
+    envsubst < "$BATS_TESTS_DIR/infisical_rotation_v1_secretproviderclass.yaml" | kubectl delete -n "$NAMESPACE" -f - || true
+    if [ -n "$PROJECT_ID" ]; then
+        infisical_delete_secret || true
+    fi
 }
 
 setup() {
@@ -292,3 +325,30 @@ teardown() {
     run wait_for_process "$WAIT_TIME" "$SLEEP_TIME" "compare_owner_count foosecret-1 '$NAMESPACE' 1"
     assert_success
 }
+
+# bats test_tags=rotate
+@test "Test auto rotation of mount contents and K8s secrets - create deployment" {
+    infisical_set_secret POST before-rotation || infisical_set_secret PATCH before-rotation
+
+    envsubst < "$BATS_TESTS_DIR/infisical_rotation_v1_secretproviderclass.yaml" | kubectl apply -n "$NAMESPACE" -f -
+    envsubst < "$BATS_TESTS_DIR/pod-rotation.yaml" | kubectl apply -n "$NAMESPACE" -f -
+
+    kubectl wait -n "$NAMESPACE" --for=condition=Ready --timeout=60s pod/secrets-store-inline-rotation
+
+    result=$(kubectl exec -n "$NAMESPACE" secrets-store-inline-rotation -- cat "/mnt/secrets-store/$ROTATION_SECRET_NAME")
+    [[ "${result//$'\r'}" == "before-rotation" ]]
+
+    result=$(kubectl get -n "$NAMESPACE" secret rotationsecret -o jsonpath="{.data.value}" | base64 -d)
+    [[ "${result//$'\r'}" == "before-rotation" ]]
+}
+
+# bats test_tags=rotate
+@test "Test auto rotation of mount contents and K8s secrets - update secret, read rotated secret" {
+    infisical_set_secret PATCH after-rotation
+
+    cmd="kubectl exec -n '$NAMESPACE' secrets-store-inline-rotation -- cat '/mnt/secrets-store/$ROTATION_SECRET_NAME' | grep after-rotation"
+    wait_for_process "$WAIT_TIME" "$SLEEP_TIME" "$cmd"
+
+    cmd="kubectl get -n '$NAMESPACE' secret rotationsecret -o jsonpath='{.data.value}' | base64 -d | grep after-rotation"
+    wait_for_process "$WAIT_TIME" "$SLEEP_TIME" "$cmd"
+}